@@ -0,0 +1,230 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+const (
+	DEFAULT_BAUD = 9600
+	DEFAULT_FPS  = 10
+
+	// SECONDS TO HOLD LAST FRAME BEFORE LOOPING
+	ANIM_HOLD_SECS = 3
+)
+
+/*
+	One frame of a baud-rate playback.
+	.Img covers only the cells that changed since the previous frame
+	(bounds are relative to the whole canvas), .Ticks is its duration
+	in frame intervals.
+*/
+type AnimFrame struct {
+	Img   *image.RGBA
+	Ticks int
+}
+
+/*
+	Bytes of input per animation frame, from .Baud & .FPS
+	(8N1 = 10 bits per byte)
+*/
+func (M UTF8Marshaller) BytesPerFrame() int {
+
+	nBaud := int(M.Baud)
+	if nBaud < 1 {
+		nBaud = DEFAULT_BAUD
+	}
+
+	if n := nBaud / 10 / M.fps(); n > 1 {
+		return n
+	}
+
+	return 1
+}
+
+/*
+	Replays `rdAnsi` through the interpreter, calling `fnFrame` with the
+	grid-so-far after every `nBytesPerFrame` bytes.  The last chunk may be
+	shorter, so the last call sees the finished grid; empty input gets none.
+*/
+func (M UTF8Marshaller) Playback(rdAnsi io.Reader, nBytesPerFrame int, fnFrame func(pGrid *Grid) error) error {
+
+	pI, E := M.NewInterpreter()
+	if E != nil {
+		return E
+	}
+
+	if nBytesPerFrame < 1 {
+		nBytesPerFrame = 1
+	}
+
+	bsChunk := make([]byte, nBytesPerFrame)
+	for !pI.Done() {
+
+		n, eRead := io.ReadFull(rdAnsi, bsChunk)
+		if n > 0 {
+
+			if _, E = pI.Write(bsChunk[:n]); E != nil {
+				return E
+			}
+
+			if E = fnFrame(&pI.Grid); E != nil {
+				return E
+			}
+		}
+
+		if (eRead == io.EOF) || (eRead == io.ErrUnexpectedEOF) {
+			break
+		} else if eRead != nil {
+			return eRead
+		}
+	}
+
+	return nil
+}
+
+/*
+	Plays back `rdAnsi` at .Baud, snapshotting every 1/.FPS seconds.
+	Identical snapshots are merged into one longer frame; every frame
+	after the first is cropped to the cells that changed.
+	Returns frames & full canvas size in pixels.
+*/
+func (M UTF8Marshaller) Frames(rdAnsi io.Reader) (sFrames []AnimFrame, rcCanvas image.Rectangle, E error) {
+
+	// PASS 1: FINAL HEIGHT (GRID ONLY GROWS)
+	bsIn, E := ioutil.ReadAll(rdAnsi)
+	if E != nil {
+		return
+	}
+
+	gFinal, E := M.Decode(bytes.NewReader(bsIn))
+	if E != nil {
+		return
+	}
+
+	nW, nH := gFinal.Width(), gFinal.Height()
+	rcCanvas = image.Rect(0, 0, nW*CELL_W, nH*CELL_H)
+
+	// PASS 2: SNAPSHOTS
+	var gPrev *Grid
+
+	E = M.Playback(bytes.NewReader(bsIn), M.BytesPerFrame(), func(pGrid *Grid) error {
+
+		pGrid = M.normalize(pGrid)
+
+		rcDirty := image.Rect(0, 0, nW, nH)
+
+		if gPrev != nil {
+
+			rcDirty = image.Rectangle{}
+			for ixRow := 0; ixRow < nH; ixRow++ {
+				for ixCol := 0; ixCol < nW; ixCol++ {
+
+					A, B := pGrid.Cell(ixCol, ixRow), gPrev.Cell(ixCol, ixRow)
+					if !A.Equal(&B) {
+						rcDirty = rcDirty.Union(image.Rect(ixCol, ixRow, ixCol+1, ixRow+1))
+					}
+				}
+			}
+		}
+
+		// DUPLICATE: EXTEND PREVIOUS FRAME
+		if rcDirty.Empty() {
+			sFrames[len(sFrames)-1].Ticks++
+			return nil
+		}
+
+		sFrames = append(sFrames, AnimFrame{
+			Img:   pGrid.RasterizeRect(M.Palette, rcDirty),
+			Ticks: 1,
+		})
+
+		G := pGrid.Clone()
+		gPrev = &G
+		return nil
+	})
+
+	// EMPTY INPUT
+	if (E == nil) && (len(sFrames) == 0) {
+		sFrames = append(sFrames, AnimFrame{Img: M.normalize(&gFinal).Rasterize(M.Palette), Ticks: 1})
+	}
+
+	return
+}
+
+func (M UTF8Marshaller) fps() int {
+
+	if M.FPS < 1 {
+		return DEFAULT_FPS
+	}
+
+	return int(M.FPS)
+}
+
+/*
+	Writes baud-rate playback of `rdAnsi` as an animated GIF
+*/
+func (M UTF8Marshaller) EncodeGIF(rdAnsi io.Reader) error {
+
+	sFrames, rcCanvas, E := M.Frames(rdAnsi)
+	if E != nil {
+		return E
+	}
+
+	nFPS := M.fps()
+	G := gif.GIF{
+		Config: image.Config{Width: rcCanvas.Dx(), Height: rcCanvas.Dy()},
+	}
+
+	nTicks := 0
+	for ix, F := range sFrames {
+
+		// 1/100s UNITS, FROM FRAME START & END SO ROUNDING DOESN'T ADD UP;
+		// AT LEAST 1, AS 0 PLAYS AT THE VIEWER'S WHIM (-fps > 100)
+		nDelay := ((nTicks+F.Ticks)*100)/nFPS - (nTicks*100)/nFPS
+		if nDelay < 1 {
+			nDelay = 1
+		}
+
+		nTicks += F.Ticks
+		if ix == (len(sFrames) - 1) {
+			nDelay += ANIM_HOLD_SECS * 100
+		}
+
+		G.Image = append(G.Image, IndexImage(F.Img))
+		G.Delay = append(G.Delay, nDelay)
+		G.Disposal = append(G.Disposal, gif.DisposalNone)
+	}
+
+	return gif.EncodeAll(M.Writer, &G)
+}
+
+/*
+	Writes baud-rate playback of `rdAnsi` as an animated PNG
+*/
+func (M UTF8Marshaller) EncodeAPNG(rdAnsi io.Reader) error {
+
+	sFrames, rcCanvas, E := M.Frames(rdAnsi)
+	if E != nil {
+		return E
+	}
+
+	nFPS := M.fps()
+	sAPNG := make([]APNGFrame, len(sFrames))
+
+	for ix, F := range sFrames {
+
+		tsDelay := (time.Duration(F.Ticks) * time.Second) / time.Duration(nFPS)
+		if ix == (len(sFrames) - 1) {
+			tsDelay += ANIM_HOLD_SECS * time.Second
+		}
+
+		sAPNG[ix] = APNGFrame{Img: F.Img, Delay: tsDelay}
+	}
+
+	return WriteAPNG(M.Writer, rcCanvas, sAPNG)
+}
@@ -0,0 +1,234 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/gif"
+	"strings"
+	"testing"
+)
+
+// 1 BYTE PER FRAME: A, B, 5 BYTES OF ESCAPE (NO CHANGE), C
+const ANIM_IN = "AB\x1b[31mC"
+
+func animRect(ixCol int) image.Rectangle {
+	return image.Rect(ixCol*CELL_W, 0, (ixCol+1)*CELL_W, CELL_H)
+}
+
+func TestFrames(t *testing.T) {
+
+	UM := UTF8Marshaller{Width: 4, Baud: 10, FPS: 1}
+
+	sFrames, rcCanvas, E := UM.Frames(strings.NewReader(ANIM_IN))
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	if rcExpect := image.Rect(0, 0, 4*CELL_W, CELL_H); rcCanvas != rcExpect {
+		t.Errorf("CANVAS: GOT %v, EXPECT %v", rcCanvas, rcExpect)
+	}
+
+	sExpect := []struct {
+		Rect  image.Rectangle
+		Ticks int
+	}{
+		{rcCanvas, 1},
+		{animRect(1), 6},
+		{animRect(2), 1},
+	}
+
+	if len(sFrames) != len(sExpect) {
+		t.Fatalf("GOT %d FRAMES, EXPECT %d", len(sFrames), len(sExpect))
+	}
+
+	G, E := UM.Decode(strings.NewReader(ANIM_IN))
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	imgFinal := UM.normalize(&G).Rasterize(UM.Palette)
+
+	for ix, F := range sFrames {
+
+		if (F.Img.Bounds() != sExpect[ix].Rect) || (F.Ticks != sExpect[ix].Ticks) {
+			t.Errorf("FRAME %d: GOT %v x%d, EXPECT %v x%d", ix, F.Img.Bounds(), F.Ticks, sExpect[ix].Rect, sExpect[ix].Ticks)
+			continue
+		}
+
+		// CHANGED CELLS ARE FINAL ONCE DRAWN
+		if ix == 0 {
+			continue
+		}
+
+		rc := F.Img.Bounds()
+		for y := rc.Min.Y; y < rc.Max.Y; y++ {
+			for x := rc.Min.X; x < rc.Max.X; x++ {
+				if F.Img.RGBAAt(x, y) != imgFinal.RGBAAt(x, y) {
+					t.Fatalf("FRAME %d: PIXEL %d,%d DIFFERS", ix, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeGIF(t *testing.T) {
+
+	sTests := []struct {
+		Baud, FPS uint
+		Delay     []int
+	}{
+		{10, 1, []int{100, 600, 100 + ANIM_HOLD_SECS*100}},
+		// 1/200s FRAMES: NO ZERO DELAYS, SAME TOTAL
+		{2000, 200, []int{1, 3, 1 + ANIM_HOLD_SECS*100}},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 4, Baud: T.Baud, FPS: T.FPS, Writer: &buf}
+
+		sFrames, rcCanvas, E := UM.Frames(strings.NewReader(ANIM_IN))
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if E = UM.EncodeGIF(strings.NewReader(ANIM_IN)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		pGIF, E := gif.DecodeAll(&buf)
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if (pGIF.Config.Width != rcCanvas.Dx()) || (pGIF.Config.Height != rcCanvas.Dy()) {
+			t.Errorf("%d FPS: GOT %dx%d, EXPECT %v", T.FPS, pGIF.Config.Width, pGIF.Config.Height, rcCanvas.Size())
+		}
+
+		if !IaEqual(pGIF.Delay, T.Delay) {
+			t.Errorf("%d FPS: GOT DELAYS %v, EXPECT %v", T.FPS, pGIF.Delay, T.Delay)
+		}
+
+		if len(pGIF.Image) != len(sFrames) {
+			t.Fatalf("%d FPS: GOT %d FRAMES, EXPECT %d", T.FPS, len(pGIF.Image), len(sFrames))
+		}
+
+		for ix, pImg := range pGIF.Image {
+
+			rc := sFrames[ix].Img.Bounds()
+			if pImg.Bounds() != rc {
+				t.Errorf("%d FPS FRAME %d: GOT %v, EXPECT %v", T.FPS, ix, pImg.Bounds(), rc)
+				continue
+			}
+
+			for y := rc.Min.Y; y < rc.Max.Y; y++ {
+				for x := rc.Min.X; x < rc.Max.X; x++ {
+
+					R1, G1, B1, _ := pImg.At(x, y).RGBA()
+					R2, G2, B2, _ := sFrames[ix].Img.At(x, y).RGBA()
+
+					if (R1 != R2) || (G1 != G2) || (B1 != B2) {
+						t.Fatalf("%d FPS FRAME %d: PIXEL %d,%d DIFFERS", T.FPS, ix, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeAPNG(t *testing.T) {
+
+	var buf bytes.Buffer
+	UM := UTF8Marshaller{Width: 4, Baud: 10, FPS: 1, Writer: &buf}
+
+	sFrames, rcCanvas, E := UM.Frames(strings.NewReader(ANIM_IN))
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	if E = UM.EncodeAPNG(strings.NewReader(ANIM_IN)); E != nil {
+		t.Fatal(E.Error())
+	}
+
+	sChunks, E := readPNGChunks(buf.Bytes())
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	if (len(sChunks) < 2) || (sChunks[0].Type != "IHDR") || (sChunks[1].Type != "acTL") {
+		t.Fatalf("EXPECT IHDR, acTL FIRST")
+	}
+
+	bsIHDR := sChunks[0].Data
+	if nW, nH := binary.BigEndian.Uint32(bsIHDR), binary.BigEndian.Uint32(bsIHDR[4:]); (int(nW) != rcCanvas.Dx()) || (int(nH) != rcCanvas.Dy()) {
+		t.Errorf("IHDR: GOT %dx%d, EXPECT %v", nW, nH, rcCanvas.Size())
+	}
+
+	if nFrames := binary.BigEndian.Uint32(sChunks[1].Data); int(nFrames) != len(sFrames) {
+		t.Errorf("acTL: GOT %d FRAMES, EXPECT %d", nFrames, len(sFrames))
+	}
+
+	sDelay := []uint16{1000, 6000, 1000 + ANIM_HOLD_SECS*1000}
+
+	// fcTL PER FRAME, fdAT AFTER THE FIRST; ONE SEQUENCE ACROSS BOTH
+	nSeq, ixFrame := uint32(0), -1
+	for _, C := range sChunks {
+
+		switch C.Type {
+
+		case "fcTL":
+
+			ixFrame++
+			if ixFrame >= len(sFrames) {
+				t.Fatalf("fcTL: MORE THAN %d FRAMES", len(sFrames))
+			}
+
+			rc := sFrames[ixFrame].Img.Bounds()
+			sGot := []uint32{
+				binary.BigEndian.Uint32(C.Data[0:]), binary.BigEndian.Uint32(C.Data[4:]), binary.BigEndian.Uint32(C.Data[8:]),
+				binary.BigEndian.Uint32(C.Data[12:]), binary.BigEndian.Uint32(C.Data[16:]),
+			}
+			sExpect := []uint32{nSeq, uint32(rc.Dx()), uint32(rc.Dy()), uint32(rc.Min.X), uint32(rc.Min.Y)}
+
+			for ix := range sGot {
+				if sGot[ix] != sExpect[ix] {
+					t.Errorf("fcTL %d: GOT %v, EXPECT %v (SEQ, W, H, X, Y)", ixFrame, sGot, sExpect)
+					break
+				}
+			}
+
+			if nNum, nDen := binary.BigEndian.Uint16(C.Data[20:]), binary.BigEndian.Uint16(C.Data[22:]); (nNum != sDelay[ixFrame]) || (nDen != 1000) {
+				t.Errorf("fcTL %d: GOT DELAY %d/%d, EXPECT %d/1000", ixFrame, nNum, nDen, sDelay[ixFrame])
+			}
+
+			nSeq++
+
+		case "fdAT":
+
+			if ixFrame < 1 {
+				t.Errorf("fdAT IN FIRST FRAME")
+			}
+
+			if n := binary.BigEndian.Uint32(C.Data); n != nSeq {
+				t.Errorf("fdAT: GOT SEQ %d, EXPECT %d", n, nSeq)
+			}
+
+			nSeq++
+
+		case "IDAT":
+
+			if ixFrame != 0 {
+				t.Errorf("IDAT OUTSIDE FIRST FRAME")
+			}
+		}
+	}
+
+	if ixFrame != len(sFrames)-1 {
+		t.Errorf("GOT %d fcTL, EXPECT %d", ixFrame+1, len(sFrames))
+	}
+
+	if sChunks[len(sChunks)-1].Type != "IEND" {
+		t.Errorf("EXPECT IEND LAST")
+	}
+}
@@ -183,6 +183,24 @@ func VF_SGR(pC *EscCode) bool {
 	return true
 }
 
+/*
+	Numeric parameter `ix` of a CSI sequence (ESC[a;b;c...),
+	`nDefault` when missing, blank or < 1
+*/
+func (pC *EscCode) Param(ix, nDefault int) int {
+
+	szParams := strings.TrimPrefix(pC.Params, "[")
+	arPrm := strings.Split(szParams, ";")
+
+	if ix < len(arPrm) {
+		if n, e := strconv.Atoi(arPrm[ix]); (e == nil) && (n > 0) {
+			return n
+		}
+	}
+
+	return nDefault
+}
+
 func (pC *EscCode) Reset() {
 
 	pC.Params = ""
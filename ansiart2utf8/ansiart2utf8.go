@@ -5,9 +5,15 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
 
 	ansi "github.com/BourgeoisBear/ansiart2utf8"
 )
@@ -16,6 +22,12 @@ func main() {
 
 	var oErr error
 
+	// TERMINAL PALETTE SET VIA OSC 4 (-osc4): RESTORED ON ERROR OR SIGNAL
+	var bPaletteSet bool
+
+	// BUFFERED STDOUT
+	var pWriter *bufio.Writer
+
 	// ERROR LOGGING
 	pLogErr := log.New(os.Stderr, "", log.Lshortfile)
 	defer func() {
@@ -23,7 +35,16 @@ func main() {
 		if oErr != nil {
 
 			pLogErr.Output(2, oErr.Error())
+
+			// RESET AFTER WHATEVER WAS WRITTEN
+			if pWriter != nil {
+				pWriter.Flush()
+			}
+
 			fmt.Fprint(os.Stdout, "\x1b[0m")
+			if bPaletteSet {
+				fmt.Fprint(os.Stdout, ansi.OSC_PALETTE_RESTORE)
+			}
 			os.Exit(1)
 		}
 	}()
@@ -55,9 +76,43 @@ OPTIONS
 	// COMMAND PARAMETERS
 	pbDebug := flag.Bool("debug", false, `DEBUG MODE: line numbering + pipe @ \n`)
 	flag.BoolVar(&UM.Translate2Xterm256, "x", false, "ANSI TO XTERM-256 COLOR SUBSTITUTION\n  (to overcome strange terminal color scheme palettes)")
-
+	flag.BoolVar(&UM.TrueColor, "truecolor", false, "ANSI TO 24-BIT COLOR SUBSTITUTION, FROM -palette\n  (exact colors, regardless of terminal theme)")
+	pszPalette := flag.String("palette", "", "PALETTE FOR -truecolor, -x & IMAGE OUTPUT: "+strings.Join(ansi.PaletteNames(), ", ")+",\n  a palette file (GIMP .gpl, Xresources, .itermcolors, Alacritty, JSON),\n  or 16 comma-separated #RRGGBB colors (0-7 dark, 8-15 bright) (default vga)")
+
+	pszCaps := flag.String("caps", "", "CONSTRAIN OUTPUT TO TERMINAL CAPABILITIES: "+strings.Join(ansi.CapsNames, ", ")+",\n  then +attr/-attr (bold, faint, italic, underline, blink, inverse, conceal, strike, bright)\n  i.e. 16,-blink (default: no constraint)")
+	flag.BoolVar(&UM.SetPalette, "osc4", false, "SET TERMINAL'S 16 COLORS TO -palette (default orig) VIA OSC 4, THEN 16-COLOR OUTPUT\n  (restored via OSC 104 after each file)")
+	flag.BoolVar(&UM.KeepPalette, "osc4exit", false, "WITH -osc4: RESTORE TERMINAL PALETTE ONLY ON EXIT")
+	flag.BoolVar(&UM.BoldBright, "boldbright", false, "BOLD + 30-37 FOREGROUND AS BRIGHT 90-97")
+	flag.BoolVar(&UM.ICEColors, "ice", false, "iCE COLORS: BLINK + 40-47 BACKGROUND AS BRIGHT 100-107, WITHOUT BLINK\n  (also on, per file, when its SAUCE asks)")
+	flag.BoolVar(&UM.ResolveAttrs, "resolve", false, "BAKE INVERSE (7) & CONCEAL (8) INTO PLAIN FG/BG COLORS\n  (image output always does)")
+	pszTransparent := flag.String("transparent", "", "TERMINAL DEFAULT BACKGROUND (49; transparent in images) FOR UNWRITTEN CELLS\n  & BACKGROUNDS OF THESE COLORS: palette indices 0-15 or #RRGGBB, comma-separated,\n  or none (i.e. 0 for black)")
+	flag.BoolVar(&UM.LightBG, "light", false, "REMAP COLORS FOR LIGHT-BACKGROUND TERMINALS\n  (black & white trade places, colors keep their hue)")
+	flag.BoolVar(&UM.Grayscale, "gray", false, "GRAYSCALE: EVERY COLOR AS THE GRAY OF ITS LIGHTNESS\n  (24-bit, or nearest with -x / -colors)")
+	flag.BoolVar(&UM.Monochrome, "mono", false, "MONOCHROME: NO COLORS, LIGHTNESS AS ░▒▓█ SHADING PER CELL")
+	pszThumb := flag.String("thumb", "", "SHRINK ART WIDER THAN N COLUMNS INTO A ▀▄ HALF-BLOCK THUMBNAIL, N COLUMNS WIDE\n  (auto = terminal width)")
+	flag.UintVar(&UM.Colors, "colors", 0, "LIMIT OUTPUT TO 8, 16 OR 256 COLORS, NEAREST MATCH (0 = NO LIMIT)")
+
+	flag.UintVar(&UM.MaxMessage, "msgsize", 0, "mirc/discord/bbcode OUTPUT: SPLIT INTO MESSAGES OF N CHARACTERS\n  (0 = PLATFORM DEFAULT: 2000 FOR discord, NO SPLIT OTHERWISE)")
 	flag.UintVar(&UM.Width, "w", 80, "LINE WRAP WIDTH")
-	flag.UintVar(&UM.MaxBytes, "bytes", 0, "MAXIMUM OUTPUT BYTES PER-ROW (0 = NO LIMIT)")
+	flag.BoolVar(&UM.Trim, "trim", false, "DROP TRAILING UNWRITTEN BLANKS FROM ROWS & TRAILING BLANK ROWS\n  (drawn & colored blanks kept)")
+	flag.BoolVar(&UM.EraseEOL, "bce", false, "WITH -trim: ERASE TRAILING COLORED BLANKS WITH ESC[K, IN THEIR COLOR\n  (for terminals with back-color-erase, i.e. xterm)")
+	flag.UintVar(&UM.MaxBytes, "bytes", 0, "MAXIMUM OUTPUT BYTES PER-ROW (0 = NO LIMIT, 400 FOR mirc)")
+	flag.StringVar(&UM.Overflow, "overflow", ansi.OVERFLOW_TRUNCATE, "ROWS OVER -bytes: "+strings.Join(ansi.OverflowModes, ", ")+"\n  (drop the rest, continue on following lines, or shorter codes & fewer colors first)")
+	flag.StringVar(&UM.Format, "format", ansi.FMT_ANSI, "OUTPUT FORMAT: "+strings.Join(ansi.OutputFormats, ", "))
+	flag.BoolVar(&UM.AppendSauce, "sauce", false, "cp437 OUTPUT: APPEND SAUCE RECORD (keeps the input's title, author, etc)")
+	flag.BoolVar(&UM.XBinFont, "xbinfont", false, "xbin OUTPUT: EMBED THE IBM VGA 8x16 FONT (ALL 256 CP437 GLYPHS)")
+	flag.BoolVar(&UM.XBinCompress, "xbincompress", true, "xbin OUTPUT: RUN-LENGTH COMPRESSION")
+	flag.StringVar(&UM.PageSize, "page", "a4", "pdf OUTPUT: PAGE SIZE: "+strings.Join(ansi.PDFPageNames(), ", "))
+	flag.StringVar(&UM.PageFit, "pagefit", ansi.PDF_FIT_NONE, "pdf OUTPUT: SCALING: "+strings.Join(ansi.PDFFitModes, ", ")+"\n  (96 DPI, fill page width, or whole art on one page)")
+	pbMore := flag.Bool("more", false, "PAGER: ONE SCREEN AT A TIME, PROMPTING BETWEEN PAGES\n  (SPACE/ENTER/n: next, b/p: back, q: quit)")
+	pnLines := flag.Uint("lines", 0, "-more SCREEN HEIGHT, PROMPT INCLUDED (0 = TERMINAL HEIGHT, ELSE 25)")
+	pszPrompt := flag.String("prompt", DEFAULT_PROMPT, "-more PROMPT: {page} & {pages} BECOME PAGE NUMBER & COUNT")
+	flag.UintVar(&UM.TileRows, "tile", 0, "SPLIT sixel/kitty/iterm2 IMAGES EVERY N TEXT ROWS (0 = ONE IMAGE)")
+	flag.StringVar(&UM.Input, "input", ansi.INPUT_AUTO, "INPUT FORMAT: "+strings.Join(ansi.InputFormats, ", "))
+	flag.Float64Var(&UM.StopTime, "until", 0, "cast INPUT: STOP REPLAY AT TIME T SECONDS (0 = END)")
+	flag.UintVar(&UM.StopEvent, "events", 0, "cast INPUT: STOP REPLAY AFTER N EVENTS (0 = ALL)")
+	flag.UintVar(&UM.Baud, "baud", ansi.DEFAULT_BAUD, "EMULATED BAUD RATE FOR gif/apng/cast PLAYBACK")
+	flag.UintVar(&UM.FPS, "fps", ansi.DEFAULT_FPS, "FRAMES PER SECOND FOR gif/apng/cast PLAYBACK")
 
 	flag.Parse()
 
@@ -73,6 +128,73 @@ OPTIONS
 		return
 	}
 
+	if !isOneOf(UM.Overflow, ansi.OverflowModes) {
+
+		oErr = fmt.Errorf("UNKNOWN OVERFLOW MODE: %s", UM.Overflow)
+		return
+	}
+
+	if _, bOk := ansi.PDFPageSizes[UM.PageSize]; !bOk {
+
+		oErr = fmt.Errorf("UNKNOWN PAGE SIZE: %s", UM.PageSize)
+		return
+	}
+
+	if !isOneOf(UM.PageFit, ansi.PDFFitModes) {
+
+		oErr = fmt.Errorf("UNKNOWN PAGE FIT: %s", UM.PageFit)
+		return
+	}
+
+	switch *pszThumb {
+
+	case "":
+
+	case "auto":
+
+		nCols, _ := termSize()
+		if nCols < 1 {
+			nCols = 80
+		}
+		UM.Thumbnail = uint(nCols)
+
+	default:
+
+		n, oE2 := strconv.ParseUint(*pszThumb, 10, 32)
+		if (oE2 != nil) || (n < 1) {
+			oErr = fmt.Errorf("BAD THUMBNAIL WIDTH: %s", *pszThumb)
+			return
+		}
+		UM.Thumbnail = uint(n)
+	}
+
+	if len(*pszCaps) > 0 {
+		if UM.Caps, oErr = ansi.ParseCaps(*pszCaps); oErr != nil {
+			return
+		}
+	}
+
+	// UNSET KEEPS -x ON ITS CLASSIC TABLE
+	if len(*pszPalette) > 0 {
+		if UM.Palette, oErr = ansi.LookupPalette(*pszPalette); oErr != nil {
+			return
+		}
+	}
+
+	if len(*pszTransparent) > 0 {
+
+		pal := UM.Palette
+		if pal == nil {
+			pal = &ansi.PalDefault
+		}
+
+		if UM.Transparent, oErr = ansi.ParseColorList(*pszTransparent, pal); oErr != nil {
+			return
+		}
+	}
+
+	bPaletteSet = UM.SetPalette
+
 	// DEBUG LOGGING
 	fnDebug := func(v ...interface{}) (int, error) {
 
@@ -84,25 +206,60 @@ OPTIONS
 	}
 
 	// BUFFER OUTPUT
-	pWriter := bufio.NewWriter(os.Stdout)
+	pWriter = bufio.NewWriter(lockedWriter{os.Stdout})
 	UM.Writer = pWriter
 
+	if bPaletteSet {
+		restoreOnSignal()
+	}
+
 	if *pbDebug {
 		UM.Debug = fnDebug
 	}
 
+	// WHAT bin/xbin OUTPUT COULDN'T HOLD
+	UM.Warn = func(v ...interface{}) (int, error) {
+		return fmt.Fprintln(os.Stderr, v...)
+	}
+
 	// PROCESS INPUT FILES
 	arFiles := flag.Args()
 	if len(arFiles) == 0 {
 		arFiles = append(arFiles, "-")
 	}
 
+	// PAGER, ONLY ONTO A TERMINAL
+	var pPager *pager
+	if *pbMore && !ansi.IsBinaryFormat(UM.Format) && isTerminal(os.Stdout) {
+
+		nLines := int(*pnLines)
+		if nLines == 0 {
+			if _, nLines = termSize(); nLines < 1 {
+				nLines = DEFAULT_PAGE_LINES
+			}
+		}
+
+		if pPager, oErr = newPager(nLines, *pszPrompt, pWriter); oErr != nil {
+			return
+		}
+	}
+
+	fnEncode := func(rdIn io.Reader) func(io.Writer) error {
+
+		return func(iWri io.Writer) error {
+			UM.Writer = iWri
+			return UM.Encode(rdIn)
+		}
+	}
+
 	for _, szFname := range arFiles {
 
+		var bQuit bool
+
 		if (szFname == "-") && (len(arFiles) == 1) {
 
 			fnDebug("PROCESSING STDIN")
-			oErr = UM.Encode(os.Stdin)
+			bQuit, oErr = pPager.Run(pWriter, fnEncode(os.Stdin))
 			if oErr != nil {
 				return
 			}
@@ -116,16 +273,73 @@ OPTIONS
 			}
 
 			fnDebug("PROCESSING ", szFname)
-			oErr = UM.Encode(pF)
+			bQuit, oErr = pPager.Run(pWriter, fnEncode(pF))
 			pF.Close()
 			if oErr != nil {
 				return
 			}
 		}
 
-		pWriter.WriteByte(ansi.CHR_LF)
+		if bQuit {
+			break
+		}
+
+		// NO TRAILING LF IN BINARY OUTPUT
+		if !ansi.IsBinaryFormat(UM.Format) {
+			pWriter.WriteByte(ansi.CHR_LF)
+		}
+		pWriter.Flush()
+	}
+
+	if UM.SetPalette && UM.KeepPalette {
+		pWriter.WriteString(ansi.OSC_PALETTE_RESTORE)
 		pWriter.Flush()
 	}
 
 	os.Exit(0)
 }
+
+// HELD FOR EACH WRITE TO STDOUT, & BY THE SIGNAL HANDLER UNTIL EXIT
+var muStdout sync.Mutex
+
+type lockedWriter struct {
+	io.Writer
+}
+
+func (W lockedWriter) Write(bs []byte) (int, error) {
+
+	muStdout.Lock()
+	defer muStdout.Unlock()
+	return W.Writer.Write(bs)
+}
+
+/*
+	On SIGINT/SIGTERM: reset colors & the terminal palette (OSC 104), then
+	exit.  Output still buffered is dropped rather than flushed from here,
+	as the render goes on meanwhile; nothing reaches STDOUT after the reset.
+*/
+func restoreOnSignal() {
+
+	chSig := make(chan os.Signal, 1)
+	signal.Notify(chSig, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+
+		<-chSig
+
+		muStdout.Lock()
+		fmt.Fprint(os.Stdout, "\x1b[0m"+ansi.OSC_PALETTE_RESTORE)
+		os.Exit(130)
+	}()
+}
+
+func isOneOf(sz string, sOptions []string) bool {
+
+	for _, szOpt := range sOptions {
+		if sz == szOpt {
+			return true
+		}
+	}
+
+	return false
+}
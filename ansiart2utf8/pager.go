@@ -0,0 +1,232 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	ansi "github.com/BourgeoisBear/ansiart2utf8"
+)
+
+const (
+	DEFAULT_PAGE_LINES = 25
+	DEFAULT_PROMPT     = "-- More -- ({page}/{pages})  SPACE: next  B: back  Q: quit"
+)
+
+// PAGER KEYS, AS READ (ARROWS & PAGE KEYS AS THEIR ESCAPES)
+const (
+	KEY_NONE = iota
+	KEY_NEXT
+	KEY_PREV
+	KEY_QUIT
+)
+
+var mapPagerKeys = map[string]int{
+	" ": KEY_NEXT, "\r": KEY_NEXT, "\n": KEY_NEXT, "n": KEY_NEXT, "f": KEY_NEXT, "j": KEY_NEXT,
+	"\x1b[6~": KEY_NEXT, "\x1b[B": KEY_NEXT,
+	"b": KEY_PREV, "p": KEY_PREV, "k": KEY_PREV,
+	"\x1b[5~": KEY_PREV, "\x1b[A": KEY_PREV,
+	"q": KEY_QUIT, "Q": KEY_QUIT, "\x1b": KEY_QUIT, "\x03": KEY_QUIT,
+}
+
+var rxPagerSGR = regexp.MustCompile("\x1b\\[[0-9;:]*m")
+
+/*
+	Screen-by-screen output, BBS style: a prompt after each page but
+	the last, waiting on a key from the terminal
+*/
+type pager struct {
+	nLines   int    // SCREEN HEIGHT, PROMPT INCLUDED
+	szPrompt string // {page} & {pages} REPLACED
+	pWri     *bufio.Writer
+	pTTY     *os.File // PUT IN RAW MODE WHILE PAGING, IF NOT NIL
+	rdKeys   *bufio.Reader
+}
+
+func newPager(nLines int, szPrompt string, pWri *bufio.Writer) (*pager, error) {
+
+	pTTY, E := openTTY()
+	if E != nil {
+		return nil, E
+	}
+
+	if nLines < 2 {
+		nLines = 2
+	}
+
+	return &pager{
+		nLines:   nLines,
+		szPrompt: szPrompt,
+		pWri:     pWri,
+		pTTY:     pTTY,
+		rdKeys:   bufio.NewReader(pTTY),
+	}, nil
+}
+
+/*
+	Pages through `bsOut` (rendered lines).  Going back clears the screen &
+	redraws.  Each page opens with the SGR state its first line was drawn
+	in, and the prompt is drawn in plain colors.  `bQuit` if the user quit.
+	A palette restore (OSC 104) at the end of `bsOut` is still sent when
+	leaving early, so the terminal isn't left in the art's colors.
+*/
+func (P *pager) Page(bsOut []byte) (bQuit bool, E error) {
+
+	sLines := strings.SplitAfter(string(bsOut), "\n")
+	if (len(sLines) > 0) && (sLines[len(sLines)-1] == "") {
+		sLines = sLines[:len(sLines)-1]
+	}
+
+	nPerPage := P.nLines - 1
+	nPages := (len(sLines) + nPerPage - 1) / nPerPage
+
+	// SGR STATE AT THE START OF EACH PAGE
+	sState := make([]string, nPages)
+	szState := ""
+	for ix, szLine := range sLines {
+
+		if ix%nPerPage == 0 {
+			sState[ix/nPerPage] = szState
+		}
+
+		for _, szSGR := range rxPagerSGR.FindAllString(szLine, -1) {
+
+			szParams := szSGR[2 : len(szSGR)-1]
+			switch {
+			case (szParams == "") || (szParams == "0"):
+				szState = ""
+			case strings.HasPrefix(szParams, "0;"):
+				szState = szSGR
+			default:
+				szState += szSGR
+			}
+		}
+	}
+
+	bDone := false
+	if bytes.Contains(bsOut, []byte(ansi.OSC_PALETTE_RESTORE)) {
+
+		defer func() {
+			if !bDone {
+				P.pWri.WriteString(ansi.OSC_PALETTE_RESTORE)
+				if EF := P.pWri.Flush(); E == nil {
+					E = EF
+				}
+			}
+		}()
+	}
+
+	if P.pTTY != nil {
+
+		fnRestore, E := ttyRaw(P.pTTY.Fd())
+		if E != nil {
+			return false, E
+		}
+		defer fnRestore()
+	}
+
+	for ixPage := 0; ixPage < nPages; {
+
+		ixEnd := (ixPage + 1) * nPerPage
+		if ixEnd > len(sLines) {
+			ixEnd = len(sLines)
+		}
+
+		P.pWri.WriteString(sState[ixPage])
+		for _, szLine := range sLines[ixPage*nPerPage : ixEnd] {
+			P.pWri.WriteString(szLine)
+		}
+
+		if ixPage == nPages-1 {
+			bDone = true
+			break
+		}
+
+		rpl := strings.NewReplacer("{page}", strconv.Itoa(ixPage+1), "{pages}", strconv.Itoa(nPages))
+		P.pWri.WriteString("\x1b[0m" + rpl.Replace(P.szPrompt))
+		if E = P.pWri.Flush(); E != nil {
+			return false, E
+		}
+
+		nKey := KEY_NONE
+		for nKey == KEY_NONE {
+			if nKey, E = P.readKey(); E != nil {
+				return false, E
+			}
+		}
+
+		// ERASE PROMPT
+		P.pWri.WriteString("\r\x1b[0m\x1b[K")
+
+		switch nKey {
+
+		case KEY_QUIT:
+			return true, P.pWri.Flush()
+
+		case KEY_NEXT:
+			ixPage++
+
+		case KEY_PREV:
+
+			if ixPage > 0 {
+				ixPage--
+			}
+			P.pWri.WriteString("\x1b[H\x1b[2J")
+		}
+	}
+
+	return false, P.pWri.Flush()
+}
+
+/*
+	One keypress (or, where the terminal can't go raw, one line)
+*/
+func (P *pager) readKey() (int, error) {
+
+	bsKey := make([]byte, 16)
+	n, E := P.rdKeys.Read(bsKey)
+	if (E != nil) && (E != io.EOF) {
+		return KEY_NONE, E
+	}
+
+	if n == 0 {
+		return KEY_QUIT, nil
+	}
+
+	// LINE INPUT: ENTER ALONE, OR FIRST CHAR
+	szKey := string(bsKey[:n])
+	if (n > 1) && !strings.HasPrefix(szKey, "\x1b") {
+
+		szKey = strings.TrimRight(szKey, "\r\n")
+		switch {
+		case szKey == "":
+			szKey = "\n"
+		case len(szKey) > 1:
+			szKey = szKey[:1]
+		}
+	}
+
+	return mapPagerKeys[szKey], nil
+}
+
+/*
+	Writes what `fnRender` writes through the pager,
+	or straight to `pWri` if `P` is nil
+*/
+func (P *pager) Run(pWri *bufio.Writer, fnRender func(io.Writer) error) (bQuit bool, E error) {
+
+	if P == nil {
+		return false, fnRender(pWri)
+	}
+
+	var buf bytes.Buffer
+	if E = fnRender(&buf); E != nil {
+		return false, E
+	}
+
+	return P.Page(buf.Bytes())
+}
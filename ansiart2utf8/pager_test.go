@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	ansi "github.com/BourgeoisBear/ansiart2utf8"
+)
+
+func TestPagerQuit(t *testing.T) {
+
+	// 2 LINES PER PAGE, 3 PAGES, PALETTE SET & RESTORED AT THE END
+	bsArt := []byte("\x1b]4;0;#000000\x1b\\a\nb\nc\nd\ne\n" + ansi.OSC_PALETTE_RESTORE)
+
+	sTests := []struct {
+		Keys    string
+		Quit    bool
+		Prompts int
+	}{
+		{"q", true, 1},
+		{"\x03", true, 1},
+		{" q", true, 2},
+		{"  ", false, 2},
+		// NO MORE KEYS
+		{"", true, 1},
+	}
+
+	// KEYS ONE AT A TIME, AS IN RAW MODE
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		P := &pager{nLines: 3, szPrompt: "{page}/{pages}", pWri: bufio.NewWriter(&buf), rdKeys: bufio.NewReader(iotest.OneByteReader(strings.NewReader(T.Keys)))}
+
+		bQuit, E := P.Page(bsArt)
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		szOut := buf.String()
+		if bQuit != T.Quit {
+			t.Errorf("%q: EXPECT QUIT %v", T.Keys, T.Quit)
+		}
+
+		if !strings.HasSuffix(szOut, ansi.OSC_PALETTE_RESTORE) || (strings.Count(szOut, ansi.OSC_PALETTE_RESTORE) != 1) {
+			t.Errorf("%q: PALETTE NOT RESTORED ONCE: %q", T.Keys, szOut)
+		}
+
+		if nPrompts := strings.Count(szOut, "/3"); nPrompts != T.Prompts {
+			t.Errorf("%q: %d PROMPTS: %q", T.Keys, nPrompts, szOut)
+		}
+	}
+}
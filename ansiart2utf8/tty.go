@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"strconv"
+)
+
+/*
+	Terminal columns & rows: from the first of STDOUT, STDERR & STDIN that
+	is a terminal, else $COLUMNS & $LINES, else 0
+*/
+func termSize() (nCols, nRows int) {
+
+	for _, pF := range []*os.File{os.Stdout, os.Stderr, os.Stdin} {
+		if nCols, nRows = ttySize(pF.Fd()); (nCols > 0) && (nRows > 0) {
+			return
+		}
+	}
+
+	nCols, _ = strconv.Atoi(os.Getenv("COLUMNS"))
+	nRows, _ = strconv.Atoi(os.Getenv("LINES"))
+	return
+}
+
+/*
+	True if `pF` is a terminal (character device), not a file or pipe
+*/
+func isTerminal(pF *os.File) bool {
+
+	fi, E := pF.Stat()
+	return (E == nil) && ((fi.Mode() & os.ModeCharDevice) != 0)
+}
+
+/*
+	The controlling terminal, for keys while STDIN may be the input
+*/
+func openTTY() (*os.File, error) {
+
+	pF, E := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if E != nil {
+		pF, E = os.Open("CONIN$")
+	}
+
+	return pF, E
+}
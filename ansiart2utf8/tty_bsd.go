@@ -0,0 +1,11 @@
+//go:build darwin || freebsd || netbsd || openbsd
+// +build darwin freebsd netbsd openbsd
+
+package main
+
+import "syscall"
+
+const (
+	IOCTL_GET_TERMIOS = syscall.TIOCGETA
+	IOCTL_SET_TERMIOS = syscall.TIOCSETA
+)
@@ -0,0 +1,8 @@
+package main
+
+import "syscall"
+
+const (
+	IOCTL_GET_TERMIOS = syscall.TCGETS
+	IOCTL_SET_TERMIOS = syscall.TCSETS
+)
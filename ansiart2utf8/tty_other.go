@@ -0,0 +1,18 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd
+
+package main
+
+/*
+	Not available here, see `termSize`
+*/
+func ttySize(fd uintptr) (nCols, nRows int) {
+	return 0, 0
+}
+
+/*
+	Not available here: keys are read a line at a time
+*/
+func ttyRaw(fd uintptr) (fnRestore func(), E error) {
+	return func() {}, nil
+}
@@ -0,0 +1,53 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd
+// +build linux darwin freebsd netbsd openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func ioctl(fd, nReq uintptr, pArg unsafe.Pointer) syscall.Errno {
+
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, nReq, uintptr(pArg))
+	return errno
+}
+
+/*
+	Terminal columns & rows via TIOCGWINSZ on `fd`, 0 if not a terminal
+*/
+func ttySize(fd uintptr) (nCols, nRows int) {
+
+	var ws struct {
+		Rows, Cols, XPixel, YPixel uint16
+	}
+
+	if ioctl(fd, uintptr(syscall.TIOCGWINSZ), unsafe.Pointer(&ws)) != 0 {
+		return 0, 0
+	}
+
+	return int(ws.Cols), int(ws.Rows)
+}
+
+/*
+	Puts terminal `fd` in raw mode: keys are read as pressed, without echo,
+	Ctrl-C included.  Returns the function to restore it.
+*/
+func ttyRaw(fd uintptr) (fnRestore func(), E error) {
+
+	var tOrig syscall.Termios
+	if errno := ioctl(fd, IOCTL_GET_TERMIOS, unsafe.Pointer(&tOrig)); errno != 0 {
+		return nil, errno
+	}
+
+	tRaw := tOrig
+	tRaw.Lflag &^= syscall.ICANON | syscall.ECHO | syscall.ISIG
+	tRaw.Cc[syscall.VMIN], tRaw.Cc[syscall.VTIME] = 1, 0
+
+	if errno := ioctl(fd, IOCTL_SET_TERMIOS, unsafe.Pointer(&tRaw)); errno != 0 {
+		return nil, errno
+	}
+
+	return func() { ioctl(fd, IOCTL_SET_TERMIOS, unsafe.Pointer(&tOrig)) }, nil
+}
@@ -0,0 +1,227 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/draw"
+	"io"
+	"time"
+)
+
+/*
+	APNG frame: .Img bounds give size & offset on the canvas
+*/
+type APNGFrame struct {
+	Img   image.Image
+	Delay time.Duration
+}
+
+type pngChunk struct {
+	Type string
+	Data []byte
+}
+
+/*
+	Splits an encoded PNG into chunks (after the signature)
+*/
+func readPNGChunks(bsPNG []byte) ([]pngChunk, error) {
+
+	const SZ_SIG = 8
+	if len(bsPNG) < SZ_SIG {
+		return nil, errors.New("PNG TOO SHORT")
+	}
+
+	sRet := []pngChunk{}
+	for ix := SZ_SIG; (ix + 12) <= len(bsPNG); {
+
+		nLen := int(binary.BigEndian.Uint32(bsPNG[ix:]))
+		if (ix + 12 + nLen) > len(bsPNG) {
+			return nil, errors.New("TRUNCATED PNG CHUNK")
+		}
+
+		sRet = append(sRet, pngChunk{
+			Type: string(bsPNG[ix+4 : ix+8]),
+			Data: bsPNG[ix+8 : ix+8+nLen],
+		})
+
+		ix += 12 + nLen
+	}
+
+	return sRet, nil
+}
+
+func writePNGChunk(pBuf *bytes.Buffer, szType string, bsData []byte) {
+
+	var bsHdr [8]byte
+	binary.BigEndian.PutUint32(bsHdr[:4], uint32(len(bsData)))
+	copy(bsHdr[4:], szType)
+	pBuf.Write(bsHdr[:])
+	pBuf.Write(bsData)
+
+	CRC := crc32.NewIEEE()
+	CRC.Write(bsHdr[4:])
+	CRC.Write(bsData)
+
+	var bsCRC [4]byte
+	binary.BigEndian.PutUint32(bsCRC[:], CRC.Sum32())
+	pBuf.Write(bsCRC[:])
+}
+
+/*
+	Delay as fraction for fcTL (16-bit numerator & denominator)
+*/
+func apngDelay(ts time.Duration) (uint16, uint16) {
+
+	if nMs := ts / time.Millisecond; nMs <= 0xFFFF {
+		return uint16(nMs), 1000
+	}
+
+	if nCs := ts / (10 * time.Millisecond); nCs <= 0xFFFF {
+		return uint16(nCs), 100
+	}
+
+	nSec := ts / time.Second
+	if nSec > 0xFFFF {
+		nSec = 0xFFFF
+	}
+
+	return uint16(nSec), 1
+}
+
+/*
+	Writes an animated PNG.  The first frame must cover `rcCanvas`,
+	later frames are drawn over it at their own bounds (no disposal, no blending).
+
+	When all frames together use 256 colors or less, they are written
+	paletted, sharing one PLTE.  Otherwise all frames must be opaque so that
+	they share one truecolor PNG color type.
+*/
+func WriteAPNG(iWri io.Writer, rcCanvas image.Rectangle, sFrames []APNGFrame) error {
+
+	if len(sFrames) == 0 {
+		return errors.New("APNG NEEDS AT LEAST ONE FRAME")
+	}
+
+	if !sFrames[0].Img.Bounds().Eq(rcCanvas) {
+		return errors.New("FIRST APNG FRAME MUST COVER CANVAS")
+	}
+
+	// SHARED PALETTE
+	sPal := color.Palette{}
+	mPal := map[color.Color]bool{}
+	for _, F := range sFrames {
+
+		rc := F.Img.Bounds()
+		for y := rc.Min.Y; (y < rc.Max.Y) && (len(sPal) <= 256); y++ {
+			for x := rc.Min.X; x < rc.Max.X; x++ {
+
+				C := color.RGBAModel.Convert(F.Img.At(x, y))
+				if !mPal[C] {
+					mPal[C] = true
+					sPal = append(sPal, C)
+				}
+			}
+		}
+	}
+
+	if len(sPal) <= 256 {
+
+		sPaletted := make([]APNGFrame, len(sFrames))
+		for ix, F := range sFrames {
+
+			pImg := image.NewPaletted(F.Img.Bounds(), sPal)
+			draw.Draw(pImg, pImg.Rect, F.Img, pImg.Rect.Min, draw.Src)
+			sPaletted[ix] = APNGFrame{Img: pImg, Delay: F.Delay}
+		}
+
+		sFrames = sPaletted
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("\x89PNG\r\n\x1a\n")
+
+	var bsIHDR []byte
+	nSeq := uint32(0)
+
+	for ixFrame, F := range sFrames {
+
+		bsPNG, E := encodePNG(F.Img)
+		if E != nil {
+			return E
+		}
+
+		sChunks, E := readPNGChunks(bsPNG)
+		if E != nil {
+			return E
+		}
+
+		if (len(sChunks) == 0) || (sChunks[0].Type != "IHDR") || (len(sChunks[0].Data) != 13) {
+			return errors.New("MISSING IHDR")
+		}
+
+		// HEADER & ANIMATION CONTROL FROM FIRST FRAME
+		if ixFrame == 0 {
+
+			bsIHDR = sChunks[0].Data
+			writePNGChunk(&buf, "IHDR", bsIHDR)
+
+			var bsACTL [8]byte
+			binary.BigEndian.PutUint32(bsACTL[0:], uint32(len(sFrames)))
+			binary.BigEndian.PutUint32(bsACTL[4:], 0) // LOOP FOREVER
+			writePNGChunk(&buf, "acTL", bsACTL[:])
+
+		} else if !bytes.Equal(bsIHDR[8:], sChunks[0].Data[8:]) {
+
+			// BIT DEPTH, COLOR TYPE, ETC.
+			return errors.New("APNG FRAMES DIFFER IN PNG COLOR TYPE")
+		}
+
+		rc := F.Img.Bounds()
+		nNum, nDen := apngDelay(F.Delay)
+
+		var bsFCTL [26]byte
+		binary.BigEndian.PutUint32(bsFCTL[0:], nSeq)
+		binary.BigEndian.PutUint32(bsFCTL[4:], uint32(rc.Dx()))
+		binary.BigEndian.PutUint32(bsFCTL[8:], uint32(rc.Dy()))
+		binary.BigEndian.PutUint32(bsFCTL[12:], uint32(rc.Min.X-rcCanvas.Min.X))
+		binary.BigEndian.PutUint32(bsFCTL[16:], uint32(rc.Min.Y-rcCanvas.Min.Y))
+		binary.BigEndian.PutUint16(bsFCTL[20:], nNum)
+		binary.BigEndian.PutUint16(bsFCTL[22:], nDen)
+		bsFCTL[24] = 0 // APNG_DISPOSE_OP_NONE
+		bsFCTL[25] = 0 // APNG_BLEND_OP_SOURCE
+		writePNGChunk(&buf, "fcTL", bsFCTL[:])
+		nSeq++
+
+		for _, C := range sChunks {
+
+			switch C.Type {
+
+			case "PLTE", "tRNS":
+				if ixFrame == 0 {
+					writePNGChunk(&buf, C.Type, C.Data)
+				}
+
+			case "IDAT":
+
+				if ixFrame == 0 {
+					writePNGChunk(&buf, "IDAT", C.Data)
+				} else {
+					bsFDAT := make([]byte, 4+len(C.Data))
+					binary.BigEndian.PutUint32(bsFDAT, nSeq)
+					copy(bsFDAT[4:], C.Data)
+					writePNGChunk(&buf, "fdAT", bsFDAT)
+					nSeq++
+				}
+			}
+		}
+	}
+
+	writePNGChunk(&buf, "IEND", nil)
+
+	_, E := iWri.Write(buf.Bytes())
+	return E
+}
@@ -0,0 +1,386 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+	"strings"
+)
+
+// SAUCE DataType OF BINARY FORMATS
+const (
+	SAUCE_DT_BINARYTEXT uint8 = 5 // .BIN, FileType IS WIDTH / 2
+	SAUCE_DT_XBIN       uint8 = 6
+)
+
+// XBIN HEADER
+const (
+	XBIN_ID       = "XBIN\x1a"
+	XBIN_PALETTE  = 1 << 0
+	XBIN_FONT     = 1 << 1
+	XBIN_COMPRESS = 1 << 2
+	XBIN_NONBLINK = 1 << 3 // iCE COLORS
+
+	// RUN TYPES, IN THE TOP 2 BITS OF A RUN'S COUNT BYTE
+	XBIN_RUN_NONE = 0x00
+	XBIN_RUN_CHAR = 0x40
+	XBIN_RUN_ATTR = 0x80
+	XBIN_RUN_BOTH = 0xC0
+	XBIN_RUN_MAX  = 64
+)
+
+/*
+	What a 16-color attribute byte couldn't hold, in cells
+*/
+type BinReport struct {
+	Colors  int         // COLORS OUTSIDE THE PALETTE, NEAREST USED
+	Attrs   int         // FAINT, ITALIC, UNDERLINE OR STRIKETHROUGH DROPPED
+	Blink   int         // BLINK DROPPED (BLINK BIT MEANS BRIGHT BG, iCE)
+	Chars   int         // CHARACTERS OUTSIDE CP437, `ASCIIFallback` USED
+	sColors map[color.RGBA]bool
+
+	// iCE COLORS NEEDED, BUT NO SAUCE RECORD TO SAY SO
+	ICEUnflagged bool
+}
+
+/*
+	One line per kind of loss, none if nothing was lost
+*/
+func (R *BinReport) Lines() []string {
+
+	sLines := []string{}
+
+	if R.Colors > 0 {
+
+		sHex := []string{}
+		for C := range R.sColors {
+			sHex = append(sHex, fmt.Sprintf("#%02x%02x%02x", C.R, C.G, C.B))
+		}
+		sort.Strings(sHex)
+
+		if len(sHex) > 8 {
+			sHex = append(sHex[:8], "...")
+		}
+
+		sLines = append(sLines, fmt.Sprintf("%d CELLS: COLORS OUTSIDE 16-COLOR PALETTE, NEAREST USED (%s)", R.Colors, strings.Join(sHex, " ")))
+	}
+
+	if R.Attrs > 0 {
+		sLines = append(sLines, fmt.Sprintf("%d CELLS: FAINT/ITALIC/UNDERLINE/STRIKETHROUGH DROPPED", R.Attrs))
+	}
+
+	if R.Blink > 0 {
+		sLines = append(sLines, fmt.Sprintf("%d CELLS: BLINK DROPPED FOR iCE COLORS", R.Blink))
+	}
+
+	if R.Chars > 0 {
+		sLines = append(sLines, fmt.Sprintf("%d CELLS: CHARACTERS OUTSIDE CP437", R.Chars))
+	}
+
+	if R.ICEUnflagged {
+		sLines = append(sLines, "BRIGHT BACKGROUNDS NEED iCE COLORS, BUT NO SAUCE RECORD FLAGS THEM (SEE -sauce): THEY WILL BLINK")
+	}
+
+	return sLines
+}
+
+/*
+	CP437 char & DOS attribute byte for every cell, rows padded to `nWidth`.
+	Bright BG anywhere turns on iCE colors (`bICE`): the blink bit
+	then means bright BG, and real blink is dropped.
+*/
+func (gr *Grid) binCells(pal *Palette, nWidth int) (bsCells []byte, bICE bool, R BinReport) {
+
+	R.sColors = map[color.RGBA]bool{}
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+
+			// AS ENCODED BELOW: BOLD INVERSE IS A BRIGHT BG
+			S := sRow[ix].Brush
+			S.ResolveAttrs()
+
+			if _, bI := dosSGR(&S, pal); bI {
+				bICE = true
+			}
+		}
+	}
+
+	const SGR_BLINK = SGR_BLNK_SLOW | SGR_BLNK_FAST
+	const SGR_LOST = SGR_FAINT | SGR_ITALIC | SGR_UNDERLINE | SGR_STRIKETHROUGH
+
+	bsCells = make([]byte, 0, nWidth*gr.Height()*2)
+	for _, sRow := range gr.grid {
+
+		for ix := 0; ix < nWidth; ix++ {
+
+			var cell GridCell
+			if ix < len(sRow) {
+				cell = sRow[ix]
+			}
+
+			S := cell.Brush
+			S.ResolveAttrs()
+
+			D, bBrightBG := dosSGR(&S, pal)
+
+			nFG, nBG := DEFAULT_FG-30, DEFAULT_BG-40
+			if len(D.Color[CIX_FG]) > 0 {
+				nFG = D.Color[CIX_FG][0] - 30
+			}
+			if len(D.Color[CIX_BG]) > 0 {
+				nBG = D.Color[CIX_BG][0] - 40
+			}
+
+			if (D.Flags & SGR_BOLD) != 0 {
+				nFG += 8
+			}
+
+			switch {
+			case bBrightBG:
+				nBG += 8
+			case ((S.Flags & SGR_BLINK) != 0) && bICE:
+				R.Blink++
+			case (S.Flags & SGR_BLINK) != 0:
+				nBG |= 8
+			}
+
+			// EXACT?
+			bLost := false
+			for CIX, n := range []int{nFG, nBG & 7} {
+
+				if (CIX == CIX_BG) && bICE {
+					n = nBG
+				}
+
+				if C := pal.Resolve(&S, CIX); C != pal[n] {
+					R.sColors[C] = true
+					bLost = true
+				}
+			}
+
+			if bLost {
+				R.Colors++
+			}
+
+			if (S.Flags & SGR_LOST) != 0 {
+				R.Attrs++
+			}
+
+			rChar := cell.Char
+			if rChar == 0 {
+				rChar = ' '
+			}
+
+			chr, bOk := map437[rChar]
+			if !bOk {
+				chr = ASCIIFallback(rChar)
+				R.Chars++
+			}
+
+			bsCells = append(bsCells, chr, byte((nBG<<4)|nFG))
+		}
+	}
+
+	return
+}
+
+/*
+	Writes the grid as a .BIN file: char/attribute pairs, then SAUCE (a copy of
+	`pSauce`, or a blank one) holding the width & iCE flag.  Odd widths get a
+	blank column, as .BIN widths are in pairs.
+*/
+func (gr *Grid) WriteBin(iWri io.Writer, pal *Palette, pSauce *Sauce) (BinReport, error) {
+
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	nWidth := gr.Width() + (gr.Width() % 2)
+	if nWidth/2 > 255 {
+		return BinReport{}, fmt.Errorf(".BIN WIDTH %d EXCEEDS 510", nWidth)
+	}
+
+	bsCells, bICE, R := gr.binCells(pal, nWidth)
+
+	S := Sauce{}
+	if pSauce != nil {
+		S = *pSauce
+	}
+
+	S.DataType, S.FileType = SAUCE_DT_BINARYTEXT, uint8(nWidth/2)
+	S.FileSize = uint32(len(bsCells))
+	S.TInfo = [4]uint16{}
+
+	S.Flags &^= SAUCE_FLAG_ICE
+	if bICE {
+		S.Flags |= SAUCE_FLAG_ICE
+	}
+
+	if _, E := iWri.Write(append(bsCells, S.Bytes()...)); E != nil {
+		return R, E
+	}
+
+	return R, nil
+}
+
+/*
+	Writes the grid as an XBin file, with `pal` (PalDefault if nil), the
+	IBM VGA 8x16 font (all 256 CP437 glyphs) with `bFont`, and compressed
+	with `bCompress`.
+	SAUCE (a copy of `pSauce`, or a blank one) is appended.
+*/
+func (gr *Grid) WriteXBin(iWri io.Writer, pal *Palette, bFont, bCompress bool, pSauce *Sauce) (BinReport, error) {
+
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	if (gr.Width() > 0xFFFF) || (gr.Height() > 0xFFFF) {
+		return BinReport{}, fmt.Errorf("XBIN SIZE %dx%d EXCEEDS 65535", gr.Width(), gr.Height())
+	}
+
+	bsCells, bICE, R := gr.binCells(pal, gr.Width())
+
+	var buf bytes.Buffer
+	buf.WriteString(XBIN_ID)
+	binary.Write(&buf, binary.LittleEndian, uint16(gr.Width()))
+	binary.Write(&buf, binary.LittleEndian, uint16(gr.Height()))
+
+	var nFlags byte = XBIN_PALETTE
+	if bFont {
+		nFlags |= XBIN_FONT
+	}
+	if bCompress {
+		nFlags |= XBIN_COMPRESS
+	}
+	if bICE {
+		nFlags |= XBIN_NONBLINK
+	}
+
+	buf.WriteByte(CELL_H)
+	buf.WriteByte(nFlags)
+
+	// 6 BITS PER CHANNEL
+	for _, C := range pal {
+		buf.Write([]byte{C.R >> 2, C.G >> 2, C.B >> 2})
+	}
+
+	if bFont {
+		for _, G := range fontVGA {
+			buf.Write(G[:])
+		}
+	}
+
+	if bCompress {
+
+		// RUNS DON'T CROSS ROWS
+		nRowBytes := gr.Width() * 2
+		for ix := 0; ix < len(bsCells); ix += nRowBytes {
+			xbinCompress(&buf, bsCells[ix:ix+nRowBytes])
+		}
+
+	} else {
+		buf.Write(bsCells)
+	}
+
+	S := Sauce{}
+	if pSauce != nil {
+		S = *pSauce
+	}
+
+	S.DataType, S.FileType = SAUCE_DT_XBIN, 0
+	S.FileSize = uint32(buf.Len())
+	S.TInfo = [4]uint16{}
+	S.Flags = 0
+
+	buf.Write(S.Bytes())
+
+	_, E := iWri.Write(buf.Bytes())
+	return R, E
+}
+
+/*
+	XBin run-length compression of one row of char/attribute pairs:
+	runs of equal cells, of equal chars, or of equal attributes,
+	otherwise literal cells
+*/
+func xbinCompress(pBuf *bytes.Buffer, bsRow []byte) {
+
+	nCells := len(bsRow) / 2
+	fnChar := func(ix int) byte { return bsRow[ix*2] }
+	fnAttr := func(ix int) byte { return bsRow[(ix*2)+1] }
+
+	// LENGTH OF RUN FROM ix WHERE fnSame HOLDS
+	fnRun := func(ix int, fnSame func(a, b int) bool) int {
+
+		n := 1
+		for (ix+n < nCells) && (n < XBIN_RUN_MAX) && fnSame(ix, ix+n) {
+			n++
+		}
+		return n
+	}
+
+	fnSameChar := func(a, b int) bool { return fnChar(a) == fnChar(b) }
+	fnSameAttr := func(a, b int) bool { return fnAttr(a) == fnAttr(b) }
+	fnSameBoth := func(a, b int) bool { return fnSameChar(a, b) && fnSameAttr(a, b) }
+
+	ixLit := 0
+	fnFlushLiteral := func(ixEnd int) {
+
+		for ixLit < ixEnd {
+
+			n := ixEnd - ixLit
+			if n > XBIN_RUN_MAX {
+				n = XBIN_RUN_MAX
+			}
+
+			pBuf.WriteByte(byte(XBIN_RUN_NONE | (n - 1)))
+			pBuf.Write(bsRow[ixLit*2 : (ixLit+n)*2])
+			ixLit += n
+		}
+	}
+
+	for ix := 0; ix < nCells; {
+
+		nBoth, nChar, nAttr := fnRun(ix, fnSameBoth), fnRun(ix, fnSameChar), fnRun(ix, fnSameAttr)
+
+		switch {
+
+		case nBoth >= 2:
+
+			fnFlushLiteral(ix)
+			pBuf.Write([]byte{byte(XBIN_RUN_BOTH | (nBoth - 1)), fnChar(ix), fnAttr(ix)})
+			ix += nBoth
+
+		case nChar >= 3:
+
+			fnFlushLiteral(ix)
+			pBuf.Write([]byte{byte(XBIN_RUN_CHAR | (nChar - 1)), fnChar(ix)})
+			for n := 0; n < nChar; n++ {
+				pBuf.WriteByte(fnAttr(ix + n))
+			}
+			ix += nChar
+
+		case nAttr >= 3:
+
+			fnFlushLiteral(ix)
+			pBuf.Write([]byte{byte(XBIN_RUN_ATTR | (nAttr - 1)), fnAttr(ix)})
+			for n := 0; n < nAttr; n++ {
+				pBuf.WriteByte(fnChar(ix + n))
+			}
+			ix += nAttr
+
+		default:
+			ix++
+			continue
+		}
+
+		ixLit = ix
+	}
+
+	fnFlushLiteral(nCells)
+}
@@ -0,0 +1,124 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestBinXBin(t *testing.T) {
+
+	for _, szFile := range []string{"fruit.ans", "fish.ans", "face.ans"} {
+
+		bsIn, E := ioutil.ReadFile(TEST_DIR + "/" + szFile)
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		var bufBin, bufXBin bytes.Buffer
+		for _, T := range []struct {
+			Format string
+			Buf    *bytes.Buffer
+		}{{FMT_BIN, &bufBin}, {FMT_XBIN, &bufXBin}} {
+
+			UM := UTF8Marshaller{Width: 80, Format: T.Format, XBinFont: true, XBinCompress: true, Writer: T.Buf}
+			if E = UM.Encode(bytes.NewReader(bsIn)); E != nil {
+				t.Fatal(E.Error())
+			}
+		}
+
+		pS := ParseSauce(bufBin.Bytes())
+		if (pS == nil) || (pS.DataType != SAUCE_DT_BINARYTEXT) || (pS.FileType != 40) {
+			t.Fatalf("%s: BAD .BIN SAUCE %+v", szFile, pS)
+		}
+
+		bsCells := bufBin.Bytes()[:pS.FileSize]
+		nHeight := len(bsCells) / 160
+
+		// UNCOMPRESS XBIN, COMPARE WITH .BIN
+		bsX := bufXBin.Bytes()
+		if !strings.HasPrefix(string(bsX), XBIN_ID) || (int(bsX[7])|int(bsX[8])<<8 != nHeight) {
+			t.Fatalf("%s: BAD XBIN HEADER", szFile)
+		}
+
+		// EMBEDDED FONT IS THE VGA ROM'S
+		bsFont := bsX[11+48 : 11+48+(256*CELL_H)]
+		for ix, G := range fontVGA {
+			if !bytes.Equal(bsFont[ix*CELL_H:(ix+1)*CELL_H], G[:]) {
+				t.Fatalf("%s: XBIN GLYPH %02X DIFFERS FROM VGA FONT", szFile, ix)
+			}
+		}
+
+		bsX = bsX[11+48+(256*CELL_H):]
+		bsOut := []byte{}
+		for len(bsOut) < len(bsCells) {
+
+			nType, n := bsX[0]&0xC0, int(bsX[0]&0x3F)+1
+			bsX = bsX[1:]
+
+			switch nType {
+
+			case XBIN_RUN_NONE:
+				bsOut, bsX = append(bsOut, bsX[:n*2]...), bsX[n*2:]
+
+			case XBIN_RUN_CHAR, XBIN_RUN_ATTR:
+
+				for ix := 0; ix < n; ix++ {
+					if nType == XBIN_RUN_CHAR {
+						bsOut = append(bsOut, bsX[0], bsX[1+ix])
+					} else {
+						bsOut = append(bsOut, bsX[1+ix], bsX[0])
+					}
+				}
+				bsX = bsX[1+n:]
+
+			case XBIN_RUN_BOTH:
+
+				for ix := 0; ix < n; ix++ {
+					bsOut = append(bsOut, bsX[0], bsX[1])
+				}
+				bsX = bsX[2:]
+			}
+		}
+
+		if !bytes.Equal(bsOut, bsCells) {
+			t.Errorf("%s: XBIN CELLS DIFFER FROM .BIN", szFile)
+		}
+
+		if pX := ParseSauce(bufXBin.Bytes()); (pX == nil) || (pX.DataType != SAUCE_DT_XBIN) {
+			t.Errorf("%s: BAD XBIN SAUCE", szFile)
+		}
+	}
+}
+
+func TestBinICE(t *testing.T) {
+
+	// BOLD INVERSE: BRIGHT BG, SO iCE, & THE BLINK IS DROPPED
+	var buf bytes.Buffer
+	sWarn := []string{}
+
+	UM := UTF8Marshaller{Width: 2, Format: FMT_BIN, Writer: &buf}
+	UM.Warn = func(sArgs ...interface{}) (int, error) {
+		sWarn = append(sWarn, fmt.Sprint(sArgs[1]))
+		return 0, nil
+	}
+
+	if E := UM.Encode(strings.NewReader("\x1b[1;7mX\x1b[0m\x1b[5mY")); E != nil {
+		t.Fatal(E.Error())
+	}
+
+	pS := ParseSauce(buf.Bytes())
+	if (pS == nil) || ((pS.Flags & SAUCE_FLAG_ICE) == 0) {
+		t.Errorf("EXPECT iCE SAUCE, GOT %+v", pS)
+	}
+
+	if bsCells := buf.Bytes()[:4]; !bytes.Equal(bsCells, []byte{'X', 0xF0, 'Y', 0x07}) {
+		t.Errorf("GOT CELLS % x", bsCells)
+	}
+
+	if (len(sWarn) != 1) || !strings.HasPrefix(sWarn[0], "1 CELLS: BLINK DROPPED") {
+		t.Errorf("GOT WARNINGS %q", sWarn)
+	}
+}
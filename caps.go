@@ -0,0 +1,227 @@
+package ansiart2utf8
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+	What the output terminal can show. `Grid.Print` degrades every SGR
+	to fit, instead of sending codes the terminal would ignore or garble.
+*/
+type Caps struct {
+	Colors int    // COLOR LIMIT (SEE `ColorLimit`), 0 = 24-BIT
+	Mono   bool   // NO COLORS AT ALL
+	Bright bool   // 90-97 / 100-107 AVAILABLE, OTHERWISE BOLD FOR BRIGHT FG
+	Attrs  uint32 // ALLOWED SGR_* FLAGS
+}
+
+const SGR_ALL uint32 = SGR_BOLD | SGR_FAINT | SGR_ITALIC | SGR_UNDERLINE |
+	SGR_BLNK_SLOW | SGR_BLNK_FAST | SGR_INVERSE | SGR_CONCEAL | SGR_STRIKETHROUGH
+
+// CAPABILITY PROFILES
+const (
+	CAPS_AUTO      = "auto"
+	CAPS_DUMB      = "dumb"
+	CAPS_MONO      = "mono"
+	CAPS_8         = "8"
+	CAPS_16        = "16"
+	CAPS_256       = "256"
+	CAPS_TRUECOLOR = "truecolor"
+)
+
+/*
+	Built-in profiles.
+	8-color terminals still get 16 colors: bright FG as bold, bright BG as dark.
+*/
+var CapsProfiles = map[string]Caps{
+	CAPS_DUMB:      {Mono: true},
+	CAPS_MONO:      {Mono: true, Attrs: SGR_ALL},
+	CAPS_8:         {Colors: COLORS_16, Attrs: SGR_ALL},
+	CAPS_16:        {Colors: COLORS_16, Bright: true, Attrs: SGR_ALL},
+	CAPS_256:       {Colors: COLORS_256, Bright: true, Attrs: SGR_ALL},
+	CAPS_TRUECOLOR: {Bright: true, Attrs: SGR_ALL},
+}
+
+var CapsNames = []string{CAPS_AUTO, CAPS_DUMB, CAPS_MONO, CAPS_8, CAPS_16, CAPS_256, CAPS_TRUECOLOR}
+
+// NAMES FOR +attr / -attr MODIFIERS
+var mCapsAttrs = map[string]uint32{
+	"bold":      SGR_BOLD,
+	"faint":     SGR_FAINT,
+	"italic":    SGR_ITALIC,
+	"underline": SGR_UNDERLINE,
+	"blink":     SGR_BLNK_SLOW | SGR_BLNK_FAST,
+	"inverse":   SGR_INVERSE,
+	"conceal":   SGR_CONCEAL,
+	"strike":    SGR_STRIKETHROUGH,
+}
+
+/*
+	Parses a profile name (see `CapsNames`), followed by optional
+	comma-separated modifiers: `+attr` / `-attr` (bold, faint, italic,
+	underline, blink, inverse, conceal, strike), or `+bright` / `-bright`.
+	i.e. "16,-blink,-italic"
+*/
+func ParseCaps(szSpec string) (*Caps, error) {
+
+	sTokens := strings.Split(strings.ToLower(strings.TrimSpace(szSpec)), ",")
+
+	var C Caps
+	if sTokens[0] == CAPS_AUTO {
+
+		C = DetectCaps(os.Getenv)
+
+	} else if P, bOk := CapsProfiles[sTokens[0]]; bOk {
+
+		C = P
+
+	} else {
+
+		return nil, fmt.Errorf("UNKNOWN CAPABILITY PROFILE: %s", sTokens[0])
+	}
+
+	for _, szTok := range sTokens[1:] {
+
+		szTok = strings.TrimSpace(szTok)
+		if len(szTok) < 2 {
+			return nil, fmt.Errorf("BAD CAPABILITY MODIFIER: %s", szTok)
+		}
+
+		bSet := szTok[0] == '+'
+		if !bSet && (szTok[0] != '-') {
+			return nil, fmt.Errorf("BAD CAPABILITY MODIFIER: %s", szTok)
+		}
+
+		if szTok[1:] == "bright" {
+			C.Bright = bSet
+			continue
+		}
+
+		fAttr, bOk := mCapsAttrs[szTok[1:]]
+		if !bOk {
+			return nil, fmt.Errorf("UNKNOWN ATTRIBUTE: %s", szTok[1:])
+		}
+
+		if bSet {
+			C.Attrs |= fAttr
+		} else {
+			C.Attrs &^= fAttr
+		}
+	}
+
+	return &C, nil
+}
+
+/*
+	Guesses terminal capabilities from NO_COLOR, COLORTERM & TERM
+*/
+func DetectCaps(fnGetenv func(string) string) Caps {
+
+	szTerm := strings.ToLower(fnGetenv("TERM"))
+
+	if szTerm == "dumb" {
+		return CapsProfiles[CAPS_DUMB]
+	}
+
+	// https://no-color.org/
+	if len(fnGetenv("NO_COLOR")) > 0 {
+		return CapsProfiles[CAPS_MONO]
+	}
+
+	switch strings.ToLower(fnGetenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return CapsProfiles[CAPS_TRUECOLOR]
+	}
+
+	switch {
+
+	case strings.Contains(szTerm, "truecolor"), strings.Contains(szTerm, "direct"):
+		return CapsProfiles[CAPS_TRUECOLOR]
+
+	case strings.Contains(szTerm, "256color"):
+		return CapsProfiles[CAPS_256]
+
+	// DEC TERMINALS: BOLD, UNDERLINE, BLINK & REVERSE ONLY
+	case strings.HasPrefix(szTerm, "vt1"), strings.HasPrefix(szTerm, "vt2"):
+
+		C := CapsProfiles[CAPS_MONO]
+		C.Attrs = SGR_BOLD | SGR_UNDERLINE | SGR_BLNK_SLOW | SGR_INVERSE
+		return C
+
+	// ANSI.SYS & FRIENDS
+	case (szTerm == "ansi"), strings.HasPrefix(szTerm, "pcansi"), strings.HasPrefix(szTerm, "cons25"):
+		return CapsProfiles[CAPS_8]
+	}
+
+	return CapsProfiles[CAPS_16]
+}
+
+/*
+	True if no SGR codes are allowed at all
+*/
+func (pC *Caps) NoSGR() bool {
+	return pC.Mono && (pC.Attrs == 0)
+}
+
+/*
+	Copy of `pOpt`, with color translations limited to what the terminal shows
+*/
+func (pC *Caps) Limit(pOpt *RenderOpts) *RenderOpts {
+
+	opt := *pOpt
+	opt.Caps = nil
+
+	if pC.Colors != 0 {
+
+		opt.TrueColor = false
+		if (opt.Colors == 0) || (opt.Colors > pC.Colors) {
+			opt.Colors = pC.Colors
+		}
+	}
+
+	if opt.Xterm256 && (ColorLimit(opt.Colors) != 0) && (ColorLimit(opt.Colors) < COLORS_256) {
+		opt.Xterm256 = false
+	}
+
+	return &opt
+}
+
+/*
+	Copy of `pS`, degraded to what the terminal shows, per color limit of `pOpt`
+	(see `Limit`)
+*/
+func (pC *Caps) Degrade(pS *SGR, pOpt *RenderOpts) SGR {
+
+	S := SGR{Flags: pS.Flags}
+
+	if !pC.Mono {
+
+		pal := pOpt.GetPalette()
+		for CIX := range S.Color {
+
+			sClr := pS.Color[CIX]
+			if len(sClr) > 0 {
+				sClr = Downsample(sClr, CIX, pOpt.Colors, pal)
+			}
+
+			// BRIGHT FG AS BOLD, BRIGHT BG AS DARK
+			if !pC.Bright && (len(sClr) == 1) {
+
+				switch v := sClr[0]; {
+				case IsBtween(v, 90, 97):
+					sClr = []int{v - 60}
+					S.Flags |= SGR_BOLD
+				case IsBtween(v, 100, 107):
+					sClr = []int{v - 60}
+				}
+			}
+
+			S.Color[CIX] = sClr
+		}
+	}
+
+	S.Flags &= pC.Attrs
+	return S
+}
@@ -0,0 +1,69 @@
+package ansiart2utf8
+
+import "testing"
+
+func TestDetectCaps(t *testing.T) {
+
+	capsVT := CapsProfiles[CAPS_MONO]
+	capsVT.Attrs = SGR_BOLD | SGR_UNDERLINE | SGR_BLNK_SLOW | SGR_INVERSE
+
+	sTests := []struct {
+		Term, ColorTerm, NoColor string
+		Expect                   Caps
+	}{
+		{"", "", "", CapsProfiles[CAPS_16]},
+		{"xterm", "", "", CapsProfiles[CAPS_16]},
+		{"xterm-256color", "", "", CapsProfiles[CAPS_256]},
+		{"screen-256color", "", "", CapsProfiles[CAPS_256]},
+		{"xterm-256color", "truecolor", "", CapsProfiles[CAPS_TRUECOLOR]},
+		{"xterm", "24bit", "", CapsProfiles[CAPS_TRUECOLOR]},
+		{"xterm-direct", "", "", CapsProfiles[CAPS_TRUECOLOR]},
+		{"vt100", "", "", capsVT},
+		{"vt220", "", "", capsVT},
+		{"ansi", "", "", CapsProfiles[CAPS_8]},
+		{"cons25", "", "", CapsProfiles[CAPS_8]},
+		{"xterm-256color", "truecolor", "1", CapsProfiles[CAPS_MONO]},
+		{"dumb", "truecolor", "", CapsProfiles[CAPS_DUMB]},
+	}
+
+	for _, T := range sTests {
+
+		mEnv := map[string]string{"TERM": T.Term, "COLORTERM": T.ColorTerm, "NO_COLOR": T.NoColor}
+		if C := DetectCaps(func(szKey string) string { return mEnv[szKey] }); C != T.Expect {
+			t.Errorf("%+v: GOT %+v", mEnv, C)
+		}
+	}
+}
+
+func TestParseCaps(t *testing.T) {
+
+	sTests := []struct {
+		In     string
+		Expect Caps
+		Err    bool
+	}{
+		{"16", CapsProfiles[CAPS_16], false},
+		{" TrueColor ", CapsProfiles[CAPS_TRUECOLOR], false},
+		{"16,-blink,-italic", Caps{Colors: COLORS_16, Bright: true, Attrs: SGR_ALL &^ (SGR_BLNK_SLOW | SGR_BLNK_FAST | SGR_ITALIC)}, false},
+		{"256,-bright", Caps{Colors: COLORS_256, Attrs: SGR_ALL}, false},
+		{"8, +bright", CapsProfiles[CAPS_16], false},
+		{"dumb,+bold", Caps{Mono: true, Attrs: SGR_BOLD}, false},
+		{"64", Caps{}, true},
+		{"16,blink", Caps{}, true},
+		{"16,-", Caps{}, true},
+		{"16,-sparkle", Caps{}, true},
+	}
+
+	for _, T := range sTests {
+
+		pC, E := ParseCaps(T.In)
+		switch {
+		case T.Err && (E == nil):
+			t.Errorf("%q: EXPECT ERROR, GOT %+v", T.In, *pC)
+		case !T.Err && (E != nil):
+			t.Errorf("%q: %s", T.In, E.Error())
+		case !T.Err && (*pC != T.Expect):
+			t.Errorf("%q: GOT %+v, EXPECT %+v", T.In, *pC, T.Expect)
+		}
+	}
+}
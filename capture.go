@@ -0,0 +1,189 @@
+package ansiart2utf8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+)
+
+// INPUT FORMATS
+const (
+	INPUT_AUTO       = "auto"
+	INPUT_ANSI       = "ansi"
+	INPUT_CAST       = "cast"
+	INPUT_TYPESCRIPT = "typescript"
+	INPUT_JSON       = "json"
+)
+
+var InputFormats = []string{INPUT_AUTO, INPUT_ANSI, INPUT_CAST, INPUT_TYPESCRIPT, INPUT_JSON}
+
+const SZ_SCRIPT_START = "Script started on "
+const SZ_SCRIPT_DONE = "Script done on "
+
+/*
+	Guesses input format from the first bytes of `pRdr` (not consumed):
+		- asciicast v2 begins with a JSON header object
+		- `script` typescripts begin with "Script started on "
+		- everything else is ANSI art
+*/
+func DetectInput(pRdr *bufio.Reader) string {
+
+	bsPeek, _ := pRdr.Peek(256)
+
+	if bytes.HasPrefix(bsPeek, []byte(SZ_SCRIPT_START)) {
+		return INPUT_TYPESCRIPT
+	}
+
+	if bytes.HasPrefix(bsPeek, []byte("{")) && bytes.Contains(bsPeek, []byte(JSON_GRID_ID)) {
+		return INPUT_JSON
+	}
+
+	if bytes.HasPrefix(bsPeek, []byte("{")) && bytes.Contains(bsPeek, []byte(`"version"`)) {
+		return INPUT_CAST
+	}
+
+	return INPUT_ANSI
+}
+
+/*
+	Replays the output events of an asciicast v2 recording into a grid.
+	Screen size comes from the header.  Replay stops before the first event
+	later than .StopTime seconds, or after .StopEvent events (when > 0).
+*/
+func (M UTF8Marshaller) DecodeCast(rdCast io.Reader) (G Grid, E error) {
+
+	pRdr := bufio.NewReader(rdCast)
+
+	// HEADER
+	bsLine, E := pRdr.ReadBytes('\n')
+	if (E != nil) && (E != io.EOF) {
+		return
+	}
+
+	var hdr CastHeader
+	if E = json.Unmarshal(bsLine, &hdr); E != nil {
+		E = fmt.Errorf("BAD ASCIICAST HEADER: %s", E.Error())
+		return
+	}
+
+	if hdr.Version != 2 {
+		E = fmt.Errorf("UNSUPPORTED ASCIICAST VERSION %d", hdr.Version)
+		return
+	}
+
+	if hdr.Width > 0 {
+		M.Width = uint(hdr.Width)
+	}
+
+	pI, E := M.NewInterpreter()
+	if E != nil {
+		return
+	}
+	pI.SetTerminal(hdr.Height)
+
+	// EVENTS: [time, code, data]
+	for ixLine, nEvents := 2, 0; ; ixLine++ {
+
+		bsLine, eRead := pRdr.ReadBytes('\n')
+
+		if bsLine = bytes.TrimSpace(bsLine); len(bsLine) > 0 {
+
+			var arEvent [3]interface{}
+			if E = json.Unmarshal(bsLine, &arEvent); E != nil {
+				E = fmt.Errorf("BAD ASCIICAST EVENT ON LINE %d: %s", ixLine, E.Error())
+				return
+			}
+
+			fTime, _ := arEvent[0].(float64)
+			szCode, _ := arEvent[1].(string)
+			szData, _ := arEvent[2].(string)
+
+			if (M.StopTime > 0) && (fTime > M.StopTime) {
+				break
+			}
+
+			if (M.StopEvent > 0) && (nEvents >= int(M.StopEvent)) {
+				break
+			}
+			nEvents++
+
+			// OUTPUT ONLY (NOT INPUT, RESIZE, MARKERS)
+			if szCode == "o" {
+				if _, E = io.WriteString(pI, szData); E != nil {
+					return
+				}
+			}
+		}
+
+		if eRead == io.EOF {
+			break
+		} else if eRead != nil {
+			E = eRead
+			return
+		}
+	}
+
+	G = pI.Grid
+	return
+}
+
+// [... COLUMNS="80" LINES="24"]
+var rxScriptDims = regexp.MustCompile(`(COLUMNS|LINES)="(\d+)"`)
+
+/*
+	Replays a `script` typescript into a grid.
+	Start/done lines are stripped; newer versions of `script` record the
+	screen size in the start line, otherwise .Width is used with no fixed height.
+	.StopTime & .StopEvent don't apply (typescripts carry no timing).
+*/
+func (M UTF8Marshaller) DecodeTypescript(rdScript io.Reader) (G Grid, E error) {
+
+	bsIn, E := ioutil.ReadAll(rdScript)
+	if E != nil {
+		return
+	}
+
+	nRows := 0
+
+	if bytes.HasPrefix(bsIn, []byte(SZ_SCRIPT_START)) {
+
+		ixEOL := bytes.IndexByte(bsIn, '\n')
+		if ixEOL < 0 {
+			ixEOL = len(bsIn) - 1
+		}
+
+		for _, sMatch := range rxScriptDims.FindAllSubmatch(bsIn[:ixEOL], -1) {
+
+			n, _ := strconv.Atoi(string(sMatch[2]))
+			if string(sMatch[1]) == "COLUMNS" {
+				M.Width = uint(n)
+			} else {
+				nRows = n
+			}
+		}
+
+		bsIn = bsIn[ixEOL+1:]
+	}
+
+	if ixDone := bytes.LastIndex(bsIn, []byte("\n"+SZ_SCRIPT_DONE)); ixDone >= 0 {
+		bsIn = bsIn[:ixDone]
+	}
+
+	pI, E := M.NewInterpreter()
+	if E != nil {
+		return
+	}
+	pI.SetTerminal(nRows)
+
+	if _, E = pI.Write(bsIn); E != nil {
+		return
+	}
+
+	G = pI.Grid
+	return
+}
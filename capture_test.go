@@ -0,0 +1,109 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCastRoundTrip(t *testing.T) {
+
+	bsArt, oE := ioutil.ReadFile("./test_data/fruit.ans")
+	if oE != nil {
+		t.Fatal(oE.Error())
+	}
+
+	var bufDirect, bufCast, bufReplay bytes.Buffer
+
+	UM := UTF8Marshaller{Width: 80, Writer: &bufDirect}
+	if oE = UM.Encode(bytes.NewReader(bsArt)); oE != nil {
+		t.Fatal(oE.Error())
+	}
+
+	UM.Writer, UM.Format = &bufCast, FMT_CAST
+	if oE = UM.Encode(bytes.NewReader(bsArt)); oE != nil {
+		t.Fatal(oE.Error())
+	}
+
+	UM.Writer, UM.Format = &bufReplay, FMT_ANSI
+	if oE = UM.Encode(&bufCast); oE != nil {
+		t.Fatal(oE.Error())
+	}
+
+	if bufDirect.String() != bufReplay.String() {
+		t.Fatal("REPLAYED CAST DIFFERS FROM DIRECT OUTPUT")
+	}
+}
+
+func TestTypescriptScroll(t *testing.T) {
+
+	const SCRIPT = "Script started on 2026-10-19 [COLUMNS=\"10\" LINES=\"2\"]\n" +
+		"\x1b]0;title\x07one\r\ntwo\r\nthree\r\n0123456789\r\nend" +
+		"\nScript done on 2026-10-19\n"
+
+	G, oE := UTF8Marshaller{Width: 80}.DecodeTypescript(strings.NewReader(SCRIPT))
+	if oE != nil {
+		t.Fatal(oE.Error())
+	}
+
+	if (G.Width() != 10) || (G.Height() != 2) {
+		t.Fatalf("BAD DIMENSIONS %d x %d", G.Width(), G.Height())
+	}
+
+	for ixRow, szExpect := range []string{"0123456789", "end"} {
+
+		sRunes := []rune{}
+		for ixCol := 0; ixCol < G.Width(); ixCol++ {
+			if C := G.Cell(ixCol, ixRow); C.Char != 0 {
+				sRunes = append(sRunes, C.Char)
+			}
+		}
+
+		if string(sRunes) != szExpect {
+			t.Fatalf("ROW %d: GOT %q, EXPECT %q", ixRow+1, string(sRunes), szExpect)
+		}
+	}
+}
+
+func TestTypescriptErase(t *testing.T) {
+
+	sTests := []struct {
+		In     string
+		Expect []string
+	}{
+		// ED 2: SCREEN CLEARED, CURSOR STAYS
+		{"abc\r\ndef\x1b[2JX", []string{"", "   X", ""}},
+		// ED 3: SCROLLBACK ONLY, SCREEN KEPT
+		{"abc\r\ndef\x1b[H\x1b[3JX", []string{"Xbc", "def", ""}},
+		// ED 0 & HOME, AS BEFORE
+		{"abc\r\ndef\x1b[H\x1b[JX", []string{"X", "", ""}},
+	}
+
+	for _, T := range sTests {
+
+		SCRIPT := "Script started on 2026-10-19 [COLUMNS=\"10\" LINES=\"3\"]\n" + T.In + "\nScript done on 2026-10-19\n"
+
+		G, oE := UTF8Marshaller{Width: 80}.DecodeTypescript(strings.NewReader(SCRIPT))
+		if oE != nil {
+			t.Fatal(oE.Error())
+		}
+
+		for ixRow, szExpect := range T.Expect {
+
+			sRunes := []rune{}
+			for ixCol := 0; ixCol < G.Width(); ixCol++ {
+
+				C := G.Cell(ixCol, ixRow)
+				if C.Char == 0 {
+					C.Char = ' '
+				}
+				sRunes = append(sRunes, C.Char)
+			}
+
+			if szRow := strings.TrimRight(string(sRunes), " "); szRow != szExpect {
+				t.Errorf("%q ROW %d: GOT %q, EXPECT %q", T.In, ixRow+1, szRow, szExpect)
+			}
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package ansiart2utf8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+/*
+	asciicast v2 header
+	https://docs.asciinema.org/manual/asciicast/v2/
+*/
+type CastHeader struct {
+	Version int               `json:"version"`
+	Width   int               `json:"width"`
+	Height  int               `json:"height"`
+	Env     map[string]string `json:"env,omitempty"`
+}
+
+/*
+	Writes `bsStream` as an asciicast v2 file: one "o" (output) event
+	of at most `nBytesPerEvent` bytes every `fInterval` seconds.
+	Events never split a valid UTF-8 sequence.  LF is sent as CRLF, as from a tty.
+*/
+func WriteCast(iWri io.Writer, hdr CastHeader, bsStream []byte, nBytesPerEvent int, fInterval float64) error {
+
+	if hdr.Version == 0 {
+		hdr.Version = 2
+	}
+
+	if nBytesPerEvent < utf8.UTFMax {
+		nBytesPerEvent = utf8.UTFMax
+	}
+
+	bsStream = bytes.ReplaceAll(bsStream, []byte("\n"), []byte("\r\n"))
+
+	pWri := bufio.NewWriter(iWri)
+	pEnc := json.NewEncoder(pWri)
+	pEnc.SetEscapeHTML(false)
+
+	// HEADER LINE
+	if E := pEnc.Encode(hdr); E != nil {
+		return E
+	}
+
+	for ixEvent := 0; len(bsStream) > 0; ixEvent++ {
+
+		nEnd := nBytesPerEvent
+		if nEnd >= len(bsStream) {
+			nEnd = len(bsStream)
+		} else {
+			// BACK UP TO START OF A RUNE, IF THERE'S ONE NEARBY (NOT IN INVALID UTF-8)
+			for n := nEnd; (n > 0) && (nEnd-n < utf8.UTFMax); n-- {
+				if utf8.RuneStart(bsStream[n]) {
+					nEnd = n
+					break
+				}
+			}
+		}
+
+		// [time, "o", data]
+		pWri.WriteString("[" + strconv.FormatFloat(float64(ixEvent)*fInterval, 'f', 6, 64) + `, "o", `)
+		bsData, E := json.Marshal(string(bsStream[:nEnd]))
+		if E != nil {
+			return E
+		}
+		pWri.Write(bsData)
+		pWri.WriteString("]\n")
+
+		bsStream = bsStream[nEnd:]
+	}
+
+	return pWri.Flush()
+}
@@ -0,0 +1,47 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteCast(t *testing.T) {
+
+	sTests := []struct {
+		In     string
+		Events []string
+	}{
+		{"abcdefgh", []string{"abcd", "efgh"}},
+		// NO RUNE SPLIT
+		{"ab██", []string{"ab", "█", "█"}},
+		{"a\n", []string{"a\r\n"}},
+		// INVALID UTF-8: SPLIT AS IS
+		{"\x80\x80\x80\x80\x80\x80", []string{"����", "��"}},
+		{"a\xe2\x96\xe2\x96\x88", []string{"a��", "█"}},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		if E := WriteCast(&buf, CastHeader{Width: 80, Height: 25}, []byte(T.In), 4, 0.1); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		sLines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		sEvents := []string{}
+		for _, szLine := range sLines[1:] {
+
+			var sEvent []interface{}
+			if E := json.Unmarshal([]byte(szLine), &sEvent); (E != nil) || (len(sEvent) != 3) {
+				t.Fatalf("%q: BAD EVENT %s", T.In, szLine)
+			}
+			sEvents = append(sEvents, sEvent[2].(string))
+		}
+
+		if strings.Join(sEvents, "|") != strings.Join(T.Events, "|") {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, sEvents, T.Events)
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package ansiart2utf8
+
+import (
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// PLATFORM LIMITS
+const (
+	// TEXT BYTES PER IRC MESSAGE: 512 LESS "PRIVMSG #channel :", PREFIX & CRLF
+	IRC_MAX_LINE = 400
+
+	// CHARACTERS PER DISCORD MESSAGE
+	DISCORD_MAX_MSG = 2000
+)
+
+// COLOR NUMBER FOR THE PLATFORM'S OWN DEFAULT
+const CHAT_DEFAULT = -1
+
+/*
+	mIRC colors: 0-15 classic, 16-98 extended
+	(https://modern.ircdocs.horse/formatting.html#colors)
+*/
+var MIRC_RGB = func() []color.RGBA {
+
+	sHex := []uint32{
+		0xFFFFFF, 0x000000, 0x00007F, 0x009300, 0xFF0000, 0x7F0000, 0x9C009C, 0xFC7F00,
+		0xFFFF00, 0x00FC00, 0x009393, 0x00FFFF, 0x0000FC, 0xFF00FF, 0x7F7F7F, 0xD2D2D2,
+
+		0x470000, 0x472100, 0x474700, 0x324700, 0x004700, 0x00472C, 0x004747, 0x002747, 0x000047, 0x2E0047, 0x470047, 0x47002A,
+		0x740000, 0x743A00, 0x747400, 0x517400, 0x007400, 0x007449, 0x007474, 0x004074, 0x000074, 0x4B0074, 0x740074, 0x740045,
+		0xB50000, 0xB56300, 0xB5B500, 0x7DB500, 0x00B500, 0x00B571, 0x00B5B5, 0x0063B5, 0x0000B5, 0x7500B5, 0xB500B5, 0xB5006B,
+		0xFF0000, 0xFF8C00, 0xFFFF00, 0xB2FF00, 0x00FF00, 0x00FFA0, 0x00FFFF, 0x008CFF, 0x0000FF, 0xA500FF, 0xFF00FF, 0xFF0098,
+		0xFF5959, 0xFFB459, 0xFFFF71, 0xCFFF60, 0x6FFF6F, 0x65FFC9, 0x6DFFFF, 0x59B4FF, 0x5959FF, 0xC459FF, 0xFF66FF, 0xFF59BC,
+		0xFF9C9C, 0xFFD39C, 0xFFFF9C, 0xE2FF9C, 0x9CFF9C, 0x9CFFDB, 0x9CFFFF, 0x9CD3FF, 0x9C9CFF, 0xDC9CFF, 0xFF9CFF, 0xFF94D3,
+		0x000000, 0x131313, 0x282828, 0x363636, 0x4D4D4D, 0x656565, 0x818181, 0x9F9F9F, 0xBCBCBC, 0xE2E2E2, 0xFFFFFF,
+	}
+
+	sRGB := make([]color.RGBA, len(sHex))
+	for ix, v := range sHex {
+		sRGB[ix] = color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+	}
+
+	return sRGB
+}()
+
+/*
+	Colors of Discord's `ansi` code blocks, for 30-37 & 40-47
+	(Solarized, per Discord's client)
+*/
+var (
+	DISCORD_FG = []color.RGBA{
+		{0x4F, 0x54, 0x5C, 255}, {0xDC, 0x32, 0x2F, 255}, {0x85, 0x99, 0x00, 255}, {0xB5, 0x89, 0x00, 255},
+		{0x26, 0x8B, 0xD2, 255}, {0xD3, 0x36, 0x82, 255}, {0x2A, 0xA1, 0x98, 255}, {0xFF, 0xFF, 0xFF, 255},
+	}
+
+	DISCORD_BG = []color.RGBA{
+		{0x00, 0x2B, 0x36, 255}, {0xCB, 0x4B, 0x16, 255}, {0x58, 0x6E, 0x75, 255}, {0x65, 0x7B, 0x83, 255},
+		{0x83, 0x94, 0x96, 255}, {0x6C, 0x71, 0xC4, 255}, {0x93, 0xA1, 0xA1, 255}, {0xFD, 0xF6, 0xE3, 255},
+
+		// CODE BLOCK BACKGROUND (NO BG CODE)
+		{0x2B, 0x2D, 0x31, 255},
+	}
+)
+
+var (
+	qMIRC16    = NewQuantizer(MIRC_RGB[:16], 0)
+	qMIRC99    = NewQuantizer(MIRC_RGB, 0)
+	qDiscordFG = NewQuantizer(DISCORD_FG, 30)
+	qDiscordBG = NewQuantizer(DISCORD_BG, 40)
+)
+
+/*
+	Cell style in a platform's terms
+*/
+type chatBrush struct {
+	FG, BG int // PLATFORM COLOR NUMBER, OR CHAT_DEFAULT
+	Flags  uint32
+}
+
+/*
+	Markup for one chat/forum platform
+*/
+type chatFormat struct {
+	Brush   func(pCell *GridCell, pPrev *chatBrush) chatBrush      // pPrev IS NIL AT LINE START
+	Cell    func(rChar rune, B chatBrush, pPrev *chatBrush) string // CODES & CHARACTER
+	Close   func(pPrev *chatBrush) string                          // AT LINE END
+	Open    string                                                 // MESSAGE START
+	End     string                                                 // MESSAGE END
+	MaxLine int                                                    // DEFAULT BYTES PER LINE
+	MaxMsg  int                                                    // DEFAULT CHARACTERS PER MESSAGE
+}
+
+// CHAT OUTPUT FORMATS
+const (
+	FMT_MIRC    = "mirc"
+	FMT_DISCORD = "discord"
+	FMT_BBCODE  = "bbcode"
+)
+
+/*
+	Renders as chat/forum markup (FMT_MIRC, FMT_DISCORD, FMT_BBCODE), colors
+	resolved through .Palette of `opt`:
+		- .MaxBytes:   bytes per line, see `chatFormat.MaxLine` if 0
+		- .Overflow:   OVERFLOW_WRAP continues long lines, others truncate
+		- .MaxMessage: characters per message/post, see `chatFormat.MaxMsg` if 0;
+		               messages are split between lines, with a blank line between
+		- .Trim:       drops trailing unwritten blanks & rows
+		- .Colors:     mIRC only, over 16 for the 99-color set
+*/
+func (gr *Grid) PrintChat(iWri io.Writer, szFormat string, opt RenderOpts) error {
+
+	pal := opt.GetPalette()
+
+	var F chatFormat
+	switch szFormat {
+	case FMT_MIRC:
+		F = mircFormat(pal, opt.Colors > COLORS_16)
+	case FMT_DISCORD:
+		F = discordFormat(pal)
+	case FMT_BBCODE:
+		F = bbcodeFormat(pal)
+	default:
+		return fmt.Errorf("UNKNOWN CHAT FORMAT: %s", szFormat)
+	}
+
+	nMaxMsg := F.MaxMsg
+	if opt.MaxMessage > 0 {
+		nMaxMsg = opt.MaxMessage
+	}
+
+	nMaxLine := F.MaxLine
+	if opt.MaxBytes > 0 {
+		nMaxLine = opt.MaxBytes
+	}
+
+	// A LINE MUST FIT IN A MESSAGE
+	nOverhead := len(F.Open) + len(F.End) + 1
+	if (nMaxMsg > 0) && ((nMaxLine == 0) || (nMaxLine > nMaxMsg-nOverhead)) {
+		nMaxLine = nMaxMsg - nOverhead
+	}
+
+	nRows := len(gr.grid)
+	for opt.Trim && (nRows > 0) && (len(trimBare(gr.grid[nRows-1])) == 0) {
+		nRows--
+	}
+
+	var sLines []string
+	for _, sRow := range gr.grid[:nRows] {
+
+		if opt.Trim {
+			sRow = trimBare(sRow)
+		}
+
+		sLines = append(sLines, F.printRow(sRow, nMaxLine, opt.Overflow == OVERFLOW_WRAP)...)
+	}
+
+	// SPLIT INTO MESSAGES
+	var sb strings.Builder
+	var szMsg string
+	for ix, szLine := range sLines {
+
+		szMsg += szLine + "\n"
+
+		bLast := ix == len(sLines)-1
+		if !bLast && (nMaxMsg > 0) {
+
+			szNext := szMsg + sLines[ix+1] + "\n"
+			if utf8.RuneCountInString(F.Open+szNext+F.End) <= nMaxMsg {
+				continue
+			}
+		} else if !bLast {
+			continue
+		}
+
+		if sb.Len() > 0 {
+			sb.WriteString("\n")
+		}
+
+		sb.WriteString(F.Open + szMsg + F.End)
+		szMsg = ""
+	}
+
+	_, E := io.WriteString(iWri, sb.String())
+	return E
+}
+
+/*
+	Lines for `sRow`, each within `nMaxLine` bytes (if > 0), codes & characters
+	never split.  Cells past the limit continue on another line with `bWrap`,
+	otherwise they're dropped.
+*/
+func (F *chatFormat) printRow(sRow GridRow, nMaxLine int, bWrap bool) (sLines []string) {
+
+	var szLine string
+	var pPrev *chatBrush
+
+	for ix := 0; ix < len(sRow); ix++ {
+
+		cell := sRow[ix]
+
+		B := F.Brush(&cell, pPrev)
+		if cell.Char == 0 {
+			cell.Char = ' '
+		}
+
+		szCell := F.Cell(cell.Char, B, pPrev)
+
+		if (nMaxLine > 0) && (len(szLine)+len(szCell)+len(F.Close(&B)) > nMaxLine) {
+
+			if !bWrap || (pPrev == nil) {
+				break
+			}
+
+			// CONTINUE ON NEXT LINE
+			sLines = append(sLines, szLine+F.Close(pPrev))
+			szLine, pPrev = "", nil
+			ix--
+			continue
+		}
+
+		szLine += szCell
+		pPrev = &B
+	}
+
+	if pPrev != nil {
+		szLine += F.Close(pPrev)
+	}
+
+	return append(sLines, szLine)
+}
+
+/*
+	Copy of `pS` with inverse & conceal baked in, and FG/BG in `pal`
+*/
+func chatColors(pS *SGR, pal *Palette) (S SGR, FG, BG color.RGBA) {
+
+	S = *pS
+	S.ResolveAttrs()
+
+	return S, pal.Resolve(&S, CIX_FG), pal.Resolve(&S, CIX_BG)
+}
+
+/*
+	mIRC: ^C color codes (nearest of 16, or 99 with `b99`), ^_ underline,
+	^] italic, ^^ strikethrough.  Lines are separate messages, so nothing to close.
+	Blanks keep the FG before them.
+*/
+func mircFormat(pal *Palette, b99 bool) chatFormat {
+
+	pQ := qMIRC16
+	if b99 {
+		pQ = qMIRC99
+	}
+
+	const (
+		CHR_COLOR     = "\x03"
+		CHR_ITALIC    = "\x1d"
+		CHR_STRIKE    = "\x1e"
+		CHR_UNDERLINE = "\x1f"
+		MIRC_DEFAULT  = 99
+	)
+
+	sToggles := []struct {
+		Flag uint32
+		Code string
+	}{
+		{SGR_UNDERLINE, CHR_UNDERLINE},
+		{SGR_ITALIC, CHR_ITALIC},
+		{SGR_STRIKETHROUGH, CHR_STRIKE},
+	}
+
+	fnBrush := func(pCell *GridCell, pPrev *chatBrush) chatBrush {
+
+		S, FG, BG := chatColors(&pCell.Brush, pal)
+
+		B := chatBrush{FG: pQ.Nearest(FG), BG: pQ.Nearest(BG), Flags: S.Flags & (SGR_UNDERLINE | SGR_ITALIC | SGR_STRIKETHROUGH)}
+		if IsTermDefault(S.Color[CIX_BG]) {
+			B.BG = MIRC_DEFAULT
+		}
+
+		if pCell.IsBlank() && (pPrev != nil) {
+			B.FG = pPrev.FG
+		}
+
+		return B
+	}
+
+	fnCell := func(rChar rune, B chatBrush, pPrev *chatBrush) string {
+
+		var sb strings.Builder
+
+		P := chatBrush{FG: CHAT_DEFAULT, BG: CHAT_DEFAULT}
+		if pPrev != nil {
+			P = *pPrev
+		}
+
+		for _, T := range sToggles {
+			if ((B.Flags ^ P.Flags) & T.Flag) != 0 {
+				sb.WriteString(T.Code)
+			}
+		}
+
+		// TWO DIGITS, SO FOLLOWING DIGITS AREN'T READ AS PART OF THE CODE;
+		// BG WITH A FOLLOWING COMMA, FOR THE SAME REASON
+		switch {
+		case (B.BG != P.BG) || ((B.FG != P.FG) && (rChar == ',')):
+			fmt.Fprintf(&sb, "%s%02d,%02d", CHR_COLOR, B.FG, B.BG)
+		case B.FG != P.FG:
+			fmt.Fprintf(&sb, "%s%02d", CHR_COLOR, B.FG)
+		}
+
+		sb.WriteRune(rChar)
+		return sb.String()
+	}
+
+	return chatFormat{
+		Brush:   fnBrush,
+		Cell:    fnCell,
+		Close:   func(*chatBrush) string { return "" },
+		MaxLine: IRC_MAX_LINE,
+	}
+}
+
+/*
+	Discord `ansi` code blocks: 8 FG & 8 BG colors of their own (nearest match),
+	underline, and reset (0) back to the block's background.
+	Each line starts from a reset, so messages can be split between any lines.
+	Blanks keep the FG before them.
+*/
+func discordFormat(pal *Palette) chatFormat {
+
+	fnBrush := func(pCell *GridCell, pPrev *chatBrush) chatBrush {
+
+		S, FG, BG := chatColors(&pCell.Brush, pal)
+
+		B := chatBrush{FG: qDiscordFG.Nearest(FG), BG: qDiscordBG.Nearest(BG), Flags: S.Flags & SGR_UNDERLINE}
+		if (B.BG == 40+len(DISCORD_BG)-1) || IsTermDefault(S.Color[CIX_BG]) {
+			B.BG = CHAT_DEFAULT
+		}
+
+		if pCell.IsBlank() && (pPrev != nil) {
+			B.FG = pPrev.FG
+		}
+
+		return B
+	}
+
+	fnCell := func(rChar rune, B chatBrush, pPrev *chatBrush) string {
+
+		// WOULD CLOSE THE CODE BLOCK
+		if rChar == '`' {
+			rChar = 'ˋ'
+		}
+
+		sCodes := []string{}
+
+		// RESET TO LEAVE UNDERLINE OR BG, OR AT LINE START
+		bReset := (pPrev == nil) || ((pPrev.Flags &^ B.Flags) != 0) || ((B.BG == CHAT_DEFAULT) && (pPrev.BG != CHAT_DEFAULT))
+		if bReset {
+			sCodes = append(sCodes, "0")
+		}
+
+		if (B.Flags != 0) && (bReset || (pPrev.Flags == 0)) {
+			sCodes = append(sCodes, "4")
+		}
+
+		if bReset || (B.FG != pPrev.FG) {
+			sCodes = append(sCodes, fmt.Sprint(B.FG))
+		}
+
+		if (B.BG != CHAT_DEFAULT) && (bReset || (B.BG != pPrev.BG)) {
+			sCodes = append(sCodes, fmt.Sprint(B.BG))
+		}
+
+		if len(sCodes) == 0 {
+			return string(rChar)
+		}
+
+		return "\x1b[" + strings.Join(sCodes, ";") + "m" + string(rChar)
+	}
+
+	return chatFormat{
+		Brush:  fnBrush,
+		Cell:   fnCell,
+		Close:  func(*chatBrush) string { return "" },
+		Open:   "```ansi\n",
+		End:    "```",
+		MaxMsg: DISCORD_MAX_MSG,
+	}
+}
+
+/*
+	BBCode: [color=#rrggbb] runs, exact colors.  There's no background markup,
+	so blanks are █ in the background color (forums also collapse spaces), and
+	blanks in the terminal's own background are uncolored no-break spaces.
+	.FG of the brush is the color shown, .BG is unused.
+*/
+func bbcodeFormat(pal *Palette) chatFormat {
+
+	fnBrush := func(pCell *GridCell, pPrev *chatBrush) chatBrush {
+
+		S, FG, BG := chatColors(&pCell.Brush, pal)
+
+		switch {
+		case !pCell.IsBlank():
+		case IsTermDefault(S.Color[CIX_BG]):
+			return chatBrush{FG: CHAT_DEFAULT, BG: CHAT_DEFAULT}
+		default:
+			FG = BG
+		}
+
+		return chatBrush{FG: (int(FG.R) << 16) | (int(FG.G) << 8) | int(FG.B), BG: CHAT_DEFAULT}
+	}
+
+	fnCell := func(rChar rune, B chatBrush, pPrev *chatBrush) string {
+
+		switch {
+		case (rChar != ' '):
+		case (B.FG == CHAT_DEFAULT):
+			rChar = '\u00a0'
+		default:
+			rChar = '█'
+		}
+
+		nPrev := CHAT_DEFAULT
+		if pPrev != nil {
+			nPrev = pPrev.FG
+		}
+
+		if B.FG == nPrev {
+			return string(rChar)
+		}
+
+		var sb strings.Builder
+		if nPrev != CHAT_DEFAULT {
+			sb.WriteString("[/color]")
+		}
+
+		if B.FG != CHAT_DEFAULT {
+			fmt.Fprintf(&sb, "[color=#%06x]", B.FG)
+		}
+
+		sb.WriteRune(rChar)
+		return sb.String()
+	}
+
+	fnClose := func(pPrev *chatBrush) string {
+
+		if pPrev.FG != CHAT_DEFAULT {
+			return "[/color]"
+		}
+		return ""
+	}
+
+	return chatFormat{
+		Brush: fnBrush,
+		Cell:  fnCell,
+		Close: fnClose,
+	}
+}
@@ -0,0 +1,112 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPrintChat(t *testing.T) {
+
+	sTests := []struct {
+		Format string
+		In     string
+		Expect string
+	}{
+		// COMMA AFTER A COLOR CODE NEEDS THE BG TOO
+		{FMT_MIRC, "\x1b[31;44mA\x1b[32m,1\x1b[4mx", "\x0305,02A\x0303,02,1\x1fx\n"},
+		// 8 COLORS, UNDERLINE & RESET ONLY; NO BACKTICKS INSIDE THE BLOCK
+		{FMT_DISCORD, "\x1b[91;44mA\x1b[4mB\x1b[24m`\x1b[0;3mC", "```ansi\n\x1b[0;31;45mA\x1b[4mB\x1b[0;31;45mˋ\x1b[36;40mC\n```"},
+		{FMT_BBCODE, "\x1b[31mAB\x1b[44m \x1b[0mC", "[color=#aa0000]AB[/color][color=#0000aa]█[/color][color=#aaaaaa]C[/color]\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 6, Format: T.Format, Trim: true, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%s %q: GOT %q, EXPECT %q", T.Format, T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestChatSplit(t *testing.T) {
+
+	// 8 COLOR CHANGES PER ROW, ~500 BYTES OF CODES
+	var sb strings.Builder
+	for ixRow := 0; ixRow < 40; ixRow++ {
+		for ixCol := 0; ixCol < 80; ixCol++ {
+			fmt.Fprintf(&sb, "\x1b[3%d;4%dm%c", (ixCol/10)%8, (ixRow+ixCol/10)%8, 'A'+ixRow%26)
+		}
+	}
+
+	rxMsg := regexp.MustCompile("^```ansi\n([^`]*)```(\n|$)")
+
+	sTests := []struct {
+		MaxMessage, MaxBytes uint
+		Limit, Line          int
+	}{
+		{0, 0, DISCORD_MAX_MSG, 0},
+		{700, 0, 700, 0},
+		{700, 200, 700, 200},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{
+			Width: 80, Format: FMT_DISCORD, Overflow: OVERFLOW_WRAP,
+			MaxMessage: T.MaxMessage, MaxBytes: T.MaxBytes, Writer: &buf,
+		}
+
+		if E := UM.Encode(strings.NewReader(sb.String())); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		// EACH MESSAGE IN ITS OWN BLOCK, WITHIN THE LIMIT
+		szOut, nMsgs, nCells := buf.String(), 0, 0
+		for len(szOut) > 0 {
+
+			sMatch := rxMsg.FindStringSubmatch(szOut)
+			if sMatch == nil {
+				t.Fatalf("%+v: MESSAGE %d NOT FENCED: %.40q", T, nMsgs, szOut)
+			}
+
+			if n := utf8.RuneCountInString(strings.TrimSuffix(sMatch[0], "\n")); n > T.Limit {
+				t.Errorf("%+v: MESSAGE %d HAS %d CHARACTERS, LIMIT %d", T, nMsgs, n, T.Limit)
+			}
+
+			for _, szLine := range strings.Split(strings.TrimSuffix(sMatch[1], "\n"), "\n") {
+
+				if (T.Line > 0) && (len(szLine) > T.Line) {
+					t.Errorf("%+v: MESSAGE %d: LINE OF %d BYTES, LIMIT %d", T, nMsgs, len(szLine), T.Line)
+				}
+
+				for _, r := range szLine {
+					if (r >= 'A') && (r <= 'Z') {
+						nCells++
+					}
+				}
+			}
+
+			szOut = szOut[len(sMatch[0]):]
+			nMsgs++
+		}
+
+		if nMsgs < 2 {
+			t.Errorf("%+v: GOT %d MESSAGES, EXPECT A SPLIT", T, nMsgs)
+		}
+
+		// NOTHING LOST BETWEEN MESSAGES
+		if nCells != 40*80 {
+			t.Errorf("%+v: GOT %d CELLS, EXPECT %d", T, nCells, 40*80)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+	"time"
+)
+
+/*
+	CP437 byte for each character of `Array437`
+*/
+var map437 = func() map[rune]byte {
+
+	m := map[rune]byte{}
+	for ix, r := range Array437 {
+		m[r] = byte(ix)
+	}
+
+	return m
+}()
+
+/*
+	Control bytes that ANSI.SYS & BBS terminals act on instead of drawing
+	(NUL, BEL, BS, TAB, LF, CR, SUB/EOF, ESC)
+*/
+var mapUnsafe437 = map[byte]bool{
+	0x00: true, 0x07: true, 0x08: true, 0x09: true,
+	0x0A: true, 0x0D: true, 0x1A: true, 0x1B: true,
+}
+
+// ATTRIBUTES ANSI.SYS KNOWS
+const SGR_DOS uint32 = SGR_BOLD | SGR_UNDERLINE | SGR_BLNK_SLOW | SGR_INVERSE | SGR_CONCEAL
+
+/*
+	CP437 byte for `rChar`; `ASCIIFallback` for characters outside CP437
+	and for those only reachable through control bytes
+*/
+func To437(rChar rune) byte {
+
+	if rChar == 0 {
+		return ' '
+	}
+
+	if chr, bOk := map437[rChar]; bOk && !mapUnsafe437[chr] {
+		return chr
+	}
+
+	return ASCIIFallback(rChar)
+}
+
+/*
+	`pS` as ANSI.SYS shows it: 16 colors (nearest in `pal`) as 30-37 / 40-47,
+	bright FG as bold, bright BG as blink (`bICE` is set, the file needs
+	iCE colors).  Default colors are left empty.  Bold doesn't brighten a
+	256 or 24-bit FG, which gets the nearest color as is.
+*/
+func dosSGR(pS *SGR, pal *Palette) (S SGR, bICE bool) {
+
+	S.Flags = pS.Flags
+	if (S.Flags & SGR_BLNK_FAST) != 0 {
+		S.Flags |= SGR_BLNK_SLOW
+	}
+	S.Flags &= SGR_DOS
+
+	if len(pS.Color[CIX_FG]) > 1 {
+		S.Flags &^= SGR_BOLD
+	}
+
+	for CIX := range S.Color {
+
+		sClr := pS.Color[CIX]
+		if (len(sClr) == 0) || IsTermDefault(sClr) {
+			continue
+		}
+
+		sClr = Downsample(sClr, CIX, COLORS_16, pal)
+
+		switch v := sClr[0]; {
+		case IsBtween(v, 90, 97):
+			sClr = []int{v - 60}
+			S.Flags |= SGR_BOLD
+		case IsBtween(v, 100, 107):
+			sClr = []int{v - 60}
+			S.Flags |= SGR_BLNK_SLOW
+			bICE = true
+		}
+
+		if !IaEqual(sClr, []int{DEFAULT_FG}) && !IaEqual(sClr, []int{DEFAULT_BG}) {
+			S.Color[CIX] = sClr
+		}
+	}
+
+	return
+}
+
+/*
+	Shortest ANSI.SYS escape from `pPrev` to `pS` (both per `dosSGR`):
+	a diff (see `SGR.ToEsc`), or a reset & set when attributes go off,
+	as ANSI.SYS has no codes to turn them off one by one.
+*/
+func dosEsc(pS, pPrev *SGR) string {
+
+	if pS.Equal(pPrev) {
+		return ""
+	}
+
+	szReset := dosReset(pS)
+
+	if (pPrev.Flags &^ pS.Flags) != 0 {
+		return szReset
+	}
+
+	if szDiff := pS.ToEsc(pPrev, true, nil); len(szDiff) <= len(szReset) {
+		return szDiff
+	}
+
+	return szReset
+}
+
+/*
+	Reset & set to `pS` (per `dosSGR`) in one escape, i.e. ESC[0;1;31m
+*/
+func dosReset(pS *SGR) string {
+
+	if szSet := pS.ToEsc(&SGR{}, true, nil); len(szSet) > 0 {
+		return "\x1b[0;" + strings.TrimPrefix(szSet, "\x1b[")
+	}
+
+	return "\x1b[0m"
+}
+
+/*
+	`pD` (per `dosSGR`) in 16 colors as shown: blink is a bright BG if `bICE`
+*/
+func dosShown(pD *SGR, bICE bool) SGR {
+
+	S := *pD
+	if bICE && ((S.Flags & SGR_BLNK_SLOW) != 0) {
+
+		S.Flags &^= SGR_BLNK_SLOW
+		if sBG := S.Color[CIX_BG]; len(sBG) > 0 {
+			S.Color[CIX_BG] = []int{sBG[0] + 60}
+		} else {
+			S.Color[CIX_BG] = []int{DEFAULT_BG + 60}
+		}
+	}
+
+	S.ResolveAttrs()
+	return S
+}
+
+/*
+	True if `pS` (per `dosSGR`) draws a blank as the black screen does,
+	so the cell can be skipped over (blink may be iCE bright black)
+*/
+func dosBlank(pS *SGR) bool {
+	return (len(pS.Color[CIX_BG]) == 0) && ((pS.Flags & (SGR_UNDERLINE | SGR_BLNK_SLOW | SGR_INVERSE)) == 0)
+}
+
+/*
+	Writes the grid as a CP437 .ANS file for ANSI.SYS & BBS terminals,
+	as few bytes as it takes:
+		- SGR changes as diffs (see `dosEsc`), colors per `dosSGR`
+		- resets only where needed: none before the first non-default
+		  brush (it sets its own), none at the end if back to default
+		- blanks on black skipped with ESC[nC, or dropped at row end
+		- CRLF line endings, except after full rows (the terminal wraps)
+	Bright BG anywhere turns on iCE colors, and blink is dropped elsewhere.
+	Appends `pSauce` if not nil, with file size, width, height & iCE flag set
+	(without it, iCE goes unflagged, see `BinReport.ICEUnflagged`).
+	Returns what ANSI.SYS couldn't show, see `BinReport`.
+*/
+func (gr *Grid) PrintCP437(iWri io.Writer, pal *Palette, pSauce *Sauce) (BinReport, error) {
+
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	const SGR_LOST = SGR_FAINT | SGR_ITALIC | SGR_STRIKETHROUGH
+
+	var buf bytes.Buffer
+	var bICE bool
+
+	R := BinReport{sColors: map[color.RGBA]bool{}}
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+			if _, bI := dosSGR(&sRow[ix].Brush, pal); bI {
+				bICE = true
+			}
+		}
+	}
+
+	// NO RESET UNTIL THE FIRST NON-DEFAULT BRUSH, WHICH BRINGS ITS OWN
+	brushPrev, bReset := SGR{}, false
+
+	sBrush := make([]SGR, gr.width)
+	for ixRow, sRow := range gr.grid {
+
+		fnBlank := func(ix int) bool {
+			return ((sRow[ix].Char == 0) || (sRow[ix].Char == ' ')) && dosBlank(&sBrush[ix])
+		}
+
+		// DOS BRUSHES; END OF ROW AFTER LAST NON-BLANK CELL
+		ixEnd := 0
+		for ix := range sRow {
+
+			pCell := &sRow[ix]
+
+			var bI bool
+			sBrush[ix], bI = dosSGR(&pCell.Brush, pal)
+
+			// BLINK BIT MEANS BRIGHT BG
+			if bICE && !bI && ((sBrush[ix].Flags & SGR_BLNK_SLOW) != 0) {
+				sBrush[ix].Flags &^= SGR_BLNK_SLOW
+				R.Blink++
+			}
+
+			// EXACT?
+			S, D := pCell.Brush, dosShown(&sBrush[ix], bICE)
+			S.ResolveAttrs()
+
+			bLost := false
+			for CIX := range S.Color {
+				if C := pal.Resolve(&S, CIX); C != pal.Resolve(&D, CIX) {
+					R.sColors[C] = true
+					bLost = true
+				}
+			}
+
+			if bLost {
+				R.Colors++
+			}
+
+			if (pCell.Brush.Flags & SGR_LOST) != 0 {
+				R.Attrs++
+			}
+
+			if chr, bOk := map437[pCell.Char]; !bOk || ((pCell.Char != 0) && mapUnsafe437[chr]) {
+				R.Chars++
+			}
+
+			if !fnBlank(ix) {
+				ixEnd = ix + 1
+			}
+		}
+
+		for ix := 0; ix < ixEnd; ix++ {
+
+			// RUN OF BLANKS: SKIP, OR WRITE IF SHORTER
+			if fnBlank(ix) {
+
+				nRun := 1
+				for fnBlank(ix + nRun) {
+					nRun++
+				}
+
+				szMove := "\x1b[C"
+				if nRun > 1 {
+					szMove = fmt.Sprintf("\x1b[%dC", nRun)
+				}
+
+				szEsc := ""
+				if !dosBlank(&brushPrev) {
+					szEsc = dosEsc(&sBrush[ix], &brushPrev)
+				}
+
+				if len(szMove) < len(szEsc)+nRun {
+					buf.WriteString(szMove)
+				} else {
+
+					buf.WriteString(szEsc)
+					if len(szEsc) > 0 {
+						brushPrev = sBrush[ix]
+					}
+					buf.WriteString(strings.Repeat(" ", nRun))
+				}
+
+				ix += nRun - 1
+				continue
+			}
+
+			if !bReset && !sBrush[ix].Equal(&SGR{}) {
+				buf.WriteString(dosReset(&sBrush[ix]))
+				bReset = true
+			} else {
+				buf.WriteString(dosEsc(&sBrush[ix], &brushPrev))
+			}
+			brushPrev = sBrush[ix]
+			buf.WriteByte(To437(sRow[ix].Char))
+		}
+
+		// FULL ROWS WRAP BY THEMSELVES
+		if (ixEnd < len(sRow)) && (ixRow < len(gr.grid)-1) {
+			buf.WriteString("\r\n")
+		}
+	}
+
+	if !brushPrev.Equal(&SGR{}) {
+		buf.WriteString("\x1b[0m")
+	}
+
+	if pSauce != nil {
+
+		S := *pSauce
+		S.FileSize = uint32(buf.Len())
+		S.TInfo[0], S.TInfo[1] = uint16(gr.width), uint16(len(gr.grid))
+
+		S.Flags &^= SAUCE_FLAG_ICE
+		if bICE {
+			S.Flags |= SAUCE_FLAG_ICE
+		}
+
+		buf.Write(S.Bytes())
+
+	} else if bICE {
+
+		R.ICEUnflagged = true
+	}
+
+	_, E := iWri.Write(buf.Bytes())
+	return R, E
+}
+
+/*
+	SAUCE for .AppendSauce: the input's (title, author, etc), or a new one
+	dated today; nil if not asked for
+*/
+func (M UTF8Marshaller) outputSauce() *Sauce {
+
+	if !M.AppendSauce {
+		return nil
+	}
+
+	pS := M.newSauce()
+	pS.DataType, pS.FileType = SAUCE_DT_CHARACTER, SAUCE_FT_ANSI
+	return pS
+}
+
+/*
+	Copy of the input's SAUCE, or a new one dated today
+*/
+func (M UTF8Marshaller) newSauce() *Sauce {
+
+	S := Sauce{Date: time.Now().Format("20060102")}
+	if M.sauceIn != nil {
+		S = *M.sauceIn
+	}
+
+	return &S
+}
@@ -0,0 +1,93 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCP437RoundTrip(t *testing.T) {
+
+	sFiles := []string{"fruit.ans", "fish.ans", "face.ans"}
+
+	for _, szName := range sFiles {
+
+		bsIn, E := ioutil.ReadFile(TEST_DIR + "/" + szName)
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		// ANS -> CP437 -> UTF8 MUST LOOK LIKE ANS -> UTF8
+		var bufAns, bufOrig, bufTrip bytes.Buffer
+
+		UM := UTF8Marshaller{Width: 80, Format: FMT_CP437, AppendSauce: true, Writer: &bufAns}
+		if E = UM.Encode(bytes.NewReader(bsIn)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if len(bufAns.Bytes()) > len(bsIn)+SAUCE_REC_LEN+1 {
+			t.Errorf("%s: %d BYTES, ORIGINAL %d", szName, len(bufAns.Bytes()), len(bsIn))
+		}
+
+		pS := ParseSauce(bufAns.Bytes())
+		if (pS == nil) || (pS.TInfo[0] != 80) || (int(pS.FileSize) != bufAns.Len()-SAUCE_REC_LEN-1) {
+			t.Errorf("%s: BAD SAUCE %+v", szName, pS)
+		}
+
+		UM = UTF8Marshaller{Width: 80, Format: FMT_PNG, Writer: &bufOrig}
+		UM.Encode(bytes.NewReader(bsIn))
+
+		UM.Writer = &bufTrip
+		UM.Encode(bytes.NewReader(bufAns.Bytes()))
+
+		if !bytes.Equal(bufOrig.Bytes(), bufTrip.Bytes()) {
+			t.Errorf("%s: ROUND TRIP DIFFERS", szName)
+		}
+	}
+}
+
+func TestCP437Report(t *testing.T) {
+
+	sTests := []struct {
+		In     string
+		Expect string
+		Warn   []string
+	}{
+		// BRIGHT BG: iCE, SO THE OTHER BLINK IS DROPPED
+		{"\x1b[104mA\x1b[0m\x1b[5mB", "\x1b[0;5;44mA\x1b[0mB", []string{"1 CELLS: BLINK DROPPED FOR iCE COLORS", "BRIGHT BACKGROUNDS NEED iCE COLORS, BUT NO SAUCE RECORD FLAGS THEM (SEE -sauce): THEY WILL BLINK"}},
+		// NO iCE: BLINK KEPT
+		{"\x1b[5mB", "\x1b[0;5mB\x1b[0m", []string{}},
+		// BOLD DOESN'T BRIGHTEN 24-BIT, EXACT
+		{"\x1b[1;38;2;170;0;0mC", "\x1b[0;31mC\x1b[0m", []string{}},
+		// NEAREST
+		{"\x1b[1;38;2;200;10;10mC", "\x1b[0;31mC\x1b[0m", []string{"1 CELLS: COLORS OUTSIDE 16-COLOR PALETTE, NEAREST USED (#c80a0a)"}},
+		// DEFAULT BRUSH THROUGHOUT: NO RESETS
+		{"\x1b[3mI", "I", []string{"1 CELLS: FAINT/ITALIC/UNDERLINE/STRIKETHROUGH DROPPED"}},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		sWarn := []string{}
+
+		UM := UTF8Marshaller{Width: 4, Format: FMT_CP437, Writer: &buf}
+		UM.Warn = func(sArgs ...interface{}) (int, error) {
+			sWarn = append(sWarn, fmt.Sprint(sArgs[1]))
+			return 0, nil
+		}
+
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+
+		if strings.Join(sWarn, "\n") != strings.Join(T.Warn, "\n") {
+			t.Errorf("%q: GOT WARNINGS %q, EXPECT %q", T.In, sWarn, T.Warn)
+		}
+	}
+}
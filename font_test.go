@@ -0,0 +1,41 @@
+package ansiart2utf8
+
+import "testing"
+
+func TestGetGlyph(t *testing.T) {
+
+	// EVERY CP437 CHARACTER DRAWN FROM ITS BYTE'S BITMAP
+	for ix, r := range Array437 {
+		if G := GetGlyph(r); G != fontVGA[ix] {
+			t.Errorf("%02X %q: NOT THE VGA GLYPH", ix, r)
+		}
+	}
+
+	var gBox Glyph
+	for y := 2; y <= CELL_H-3; y++ {
+		gBox[y] = 0x42
+	}
+	gBox[2], gBox[CELL_H-3] = 0x7E, 0x7E
+
+	sTests := []struct {
+		In     rune
+		Expect Glyph
+	}{
+		{0, Glyph{}},
+		{' ', Glyph{}},
+		{'█', Glyph{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{'▀', Glyph{0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF}},
+		{'▌', Glyph{0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0, 0xF0}},
+		{'│', Glyph{0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18, 0x18}},
+		{'A', Glyph{0x00, 0x00, 0x10, 0x38, 0x6C, 0xC6, 0xC6, 0xFE, 0xC6, 0xC6, 0xC6, 0xC6}},
+		// NOT IN CP437
+		{'✓', gBox},
+		{'€', gBox},
+	}
+
+	for _, T := range sTests {
+		if G := GetGlyph(T.In); G != T.Expect {
+			t.Errorf("%q: GOT %02X, EXPECT %02X", T.In, G, T.Expect)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"io"
+	"strconv"
+)
+
+// MAX BASE64 PAYLOAD PER KITTY APC CHUNK
+const KITTY_CHUNK = 4096
+
+/*
+	Splits `img` into horizontal strips of `nTileH` pixels
+	(for images taller than the screen).  Returns `img` as-is when `nTileH` < 1.
+*/
+func TileImage(img *image.RGBA, nTileH int) []image.Image {
+
+	rc := img.Bounds()
+	if (nTileH < 1) || (rc.Dy() <= nTileH) {
+		return []image.Image{img}
+	}
+
+	sRet := []image.Image{}
+	for y := rc.Min.Y; y < rc.Max.Y; y += nTileH {
+
+		yEnd := y + nTileH
+		if yEnd > rc.Max.Y {
+			yEnd = rc.Max.Y
+		}
+
+		sRet = append(sRet, img.SubImage(image.Rect(rc.Min.X, y, rc.Max.X, yEnd)))
+	}
+
+	return sRet
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+
+	var buf bytes.Buffer
+	if E := png.Encode(&buf, img); E != nil {
+		return nil, E
+	}
+
+	return buf.Bytes(), nil
+}
+
+/*
+	Writes `img` as a PNG via the kitty graphics protocol:
+	base64 payload split across APC chunks, m=1 on all but the last
+*/
+func WriteKitty(iWri io.Writer, img image.Image) error {
+
+	bsPNG, E := encodePNG(img)
+	if E != nil {
+		return E
+	}
+
+	szB64 := base64.StdEncoding.EncodeToString(bsPNG)
+
+	var buf bytes.Buffer
+	for ix := 0; ix < len(szB64); ix += KITTY_CHUNK {
+
+		ixEnd := ix + KITTY_CHUNK
+		bMore := 1
+		if ixEnd >= len(szB64) {
+			ixEnd = len(szB64)
+			bMore = 0
+		}
+
+		buf.WriteString("\x1b_G")
+
+		// TRANSMIT & DISPLAY PNG, SUPPRESS RESPONSES
+		if ix == 0 {
+			buf.WriteString("a=T,f=100,q=2,")
+		}
+
+		buf.WriteString("m=" + strconv.Itoa(bMore) + ";")
+		buf.WriteString(szB64[ix:ixEnd])
+		buf.WriteString("\x1b\\")
+	}
+
+	_, E = iWri.Write(buf.Bytes())
+	return E
+}
+
+/*
+	Writes `img` as a PNG via iTerm2's inline image protocol (OSC 1337 File=)
+*/
+func WriteITerm2(iWri io.Writer, img image.Image) error {
+
+	bsPNG, E := encodePNG(img)
+	if E != nil {
+		return E
+	}
+
+	rc := img.Bounds()
+
+	var buf bytes.Buffer
+	buf.WriteString("\x1b]1337;File=inline=1;size=" + strconv.Itoa(len(bsPNG)))
+	buf.WriteString(";width=" + strconv.Itoa(rc.Dx()) + "px;height=" + strconv.Itoa(rc.Dy()) + "px")
+	buf.WriteString(";preserveAspectRatio=1:")
+	buf.WriteString(base64.StdEncoding.EncodeToString(bsPNG))
+	buf.WriteString("\a")
+
+	_, E = iWri.Write(buf.Bytes())
+	return E
+}
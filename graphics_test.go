@@ -0,0 +1,129 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image/png"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+var (
+	rxKitty  = regexp.MustCompile("^\x1b_G(a=T,f=100,q=2,)?m=([01]);([A-Za-z0-9+/=]*)\x1b\\\\")
+	rxITerm2 = regexp.MustCompile("^\x1b]1337;File=inline=1;size=(\\d+);width=(\\d+)px;height=(\\d+)px;preserveAspectRatio=1:([A-Za-z0-9+/=]*)\a$")
+)
+
+func TestKittyITerm2(t *testing.T) {
+
+	bsIn, E := ioutil.ReadFile(TEST_DIR + "/fruit.ans")
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	UM := UTF8Marshaller{Width: 80}
+	G, E := UM.Decode(bytes.NewReader(bsIn))
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	imgFull := UM.normalize(&G).Rasterize(UM.Palette)
+
+	sTests := []struct {
+		Format   string
+		TileRows uint
+		Tiles    int
+	}{
+		{FMT_KITTY, 0, 1},
+		{FMT_KITTY, 10, (G.Height() + 9) / 10},
+		{FMT_ITERM2, 0, 1},
+		{FMT_ITERM2, 10, (G.Height() + 9) / 10},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 80, Format: T.Format, TileRows: T.TileRows, Writer: &buf}
+		if E = UM.Encode(bytes.NewReader(bsIn)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		sOut := strings.Split(buf.String(), "\n")
+		if len(sOut) != T.Tiles {
+			t.Errorf("%s/%d: GOT %d TILES, EXPECT %d", T.Format, T.TileRows, len(sOut), T.Tiles)
+			continue
+		}
+
+		for ixTile, szOut := range sOut {
+
+			// PAYLOAD
+			szB64 := ""
+			if T.Format == FMT_KITTY {
+
+				for ixChunk := 0; len(szOut) > 0; ixChunk++ {
+
+					sMatch := rxKitty.FindStringSubmatch(szOut)
+					if (sMatch == nil) || ((ixChunk == 0) != (sMatch[1] != "")) || (len(sMatch[3]) > KITTY_CHUNK) {
+						t.Fatalf("%s TILE %d CHUNK %d: BAD APC %.40q", T.Format, ixTile, ixChunk, szOut)
+					}
+
+					szOut = szOut[len(sMatch[0]):]
+					if bLast := len(szOut) == 0; bLast != (sMatch[2] == "0") {
+						t.Errorf("%s TILE %d CHUNK %d: BAD m=%s", T.Format, ixTile, ixChunk, sMatch[2])
+					}
+
+					szB64 += sMatch[3]
+				}
+
+			} else {
+
+				sMatch := rxITerm2.FindStringSubmatch(szOut)
+				if sMatch == nil {
+					t.Fatalf("%s TILE %d: BAD OSC %.40q", T.Format, ixTile, szOut)
+				}
+
+				szB64 = sMatch[4]
+				if nSize, _ := strconv.Atoi(sMatch[1]); nSize != base64.StdEncoding.DecodedLen(len(szB64))-strings.Count(szB64, "=") {
+					t.Errorf("%s TILE %d: BAD SIZE %d", T.Format, ixTile, nSize)
+				}
+			}
+
+			bsPNG, E := base64.StdEncoding.DecodeString(szB64)
+			if E != nil {
+				t.Fatal(E.Error())
+			}
+
+			img, E := png.Decode(bytes.NewReader(bsPNG))
+			if E != nil {
+				t.Fatal(E.Error())
+			}
+
+			// SAME PIXELS AS THAT STRIP OF THE FULL RASTER
+			rc := imgFull.Bounds()
+			if T.TileRows > 0 {
+				rc.Min.Y = ixTile * int(T.TileRows) * CELL_H
+				rc.Max.Y = rc.Min.Y + int(T.TileRows)*CELL_H
+				rc = rc.Intersect(imgFull.Bounds())
+			}
+
+			if img.Bounds().Size() != rc.Size() {
+				t.Fatalf("%s TILE %d: GOT %v, EXPECT %v", T.Format, ixTile, img.Bounds().Size(), rc.Size())
+			}
+
+			ptMin := img.Bounds().Min
+			for y := 0; y < rc.Dy(); y++ {
+				for x := 0; x < rc.Dx(); x++ {
+
+					R1, G1, B1, _ := img.At(ptMin.X+x, ptMin.Y+y).RGBA()
+					R2, G2, B2, _ := imgFull.At(rc.Min.X+x, rc.Min.Y+y).RGBA()
+
+					if (R1 != R2) || (G1 != G2) || (B1 != B2) {
+						t.Fatalf("%s TILE %d: PIXEL %d,%d DIFFERS", T.Format, ixTile, x, y)
+					}
+				}
+			}
+		}
+	}
+}
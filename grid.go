@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type GridPos struct {
@@ -31,13 +32,19 @@ func (pos *GridPos) Denorm() (X, Y int) {
 }
 
 type GridCell struct {
-	Char  rune
-	Brush SGR
+	Char    rune
+	Brush   SGR
+	Written bool // DRAWN ON (SPACES INCLUDED), NOT JUST ALLOCATED OR ERASED
 }
 
 func (gc *GridCell) ClearCell() {
 	gc.Char = 0
 	gc.Brush = SGR{}
+	gc.Written = false
+}
+
+func (gc *GridCell) Equal(pO *GridCell) bool {
+	return (gc.Char == pO.Char) && gc.Brush.Equal(&pO.Brush)
 }
 
 type GridRow []GridCell
@@ -70,6 +77,35 @@ func (gr *Grid) Height() int {
 	return int(len(gr.grid))
 }
 
+func (gr *Grid) Width() int {
+	return int(gr.width)
+}
+
+/*
+	Cell at 0-based column/row; blank cell if outside the grid.
+*/
+func (gr *Grid) Cell(ixCol, ixRow int) GridCell {
+
+	if (ixRow < 0) || (ixRow >= len(gr.grid)) || (ixCol < 0) || (ixCol >= int(gr.width)) {
+		return GridCell{}
+	}
+
+	return gr.grid[ixRow][ixCol]
+}
+
+/*
+	Copy of grid that shares no rows with `gr`
+*/
+func (gr *Grid) Clone() Grid {
+
+	G := Grid{width: gr.width, grid: make([]GridRow, len(gr.grid))}
+	for ix := range gr.grid {
+		G.grid[ix] = append(GridRow(nil), gr.grid[ix]...)
+	}
+
+	return G
+}
+
 func (gr *Grid) Inc(pos *GridPos, nAmt int) {
 
 	if pos == nil {
@@ -235,6 +271,48 @@ func (gr *Grid) Touch(nHeight int) {
 	gr.grid = sGrid
 }
 
+/*
+	Scrolls grid contents up `n` rows, keeping its height.
+	Top rows are discarded, blank rows appear at the bottom.
+*/
+func (gr *Grid) ScrollUp(n int) {
+
+	nHgt := gr.Height()
+	if n > nHgt {
+		n = nHgt
+	}
+
+	if n < 1 {
+		return
+	}
+
+	copy(gr.grid, gr.grid[n:])
+	for ix := nHgt - n; ix < nHgt; ix++ {
+		gr.grid[ix] = make(GridRow, gr.width)
+	}
+}
+
+/*
+	Scrolls grid contents down `n` rows, keeping its height.
+	Bottom rows are discarded, blank rows appear at the top.
+*/
+func (gr *Grid) ScrollDown(n int) {
+
+	nHgt := gr.Height()
+	if n > nHgt {
+		n = nHgt
+	}
+
+	if n < 1 {
+		return
+	}
+
+	copy(gr.grid[n:], gr.grid[:nHgt-n])
+	for ix := 0; ix < n; ix++ {
+		gr.grid[ix] = make(GridRow, gr.width)
+	}
+}
+
 func (gr *Grid) Put(pos GridPos, rChar rune, sgrCodes SGR) error {
 
 	// CONVERT TO 1-BASED TO 0-BASED
@@ -259,74 +337,280 @@ func (gr *Grid) Put(pos GridPos, rChar rune, sgrCodes SGR) error {
 	row := gr.grid[ixLine]
 	row[ixCol].Char = rChar
 	row[ixCol].Brush = sgrCodes
+	row[ixCol].Written = true
 
 	return nil
 }
 
-func (gr *Grid) Print(iWri io.Writer, nRowBytes int, bDebug, bXterm256, bFakeEsc bool) {
+func (gr *Grid) Print(iWri io.Writer, opt RenderOpts) {
 
 	/*
 		NOTE: CAN'T ESC[nC COMPRESS BECAUSE OF TERMINAL BACKGROUND COLOR
 	*/
 
-	const STR_CLEAR = "\x1b[0m"
+	// TERMINAL PALETTE CARRIES THE COLORS
+	if opt.SetPalette {
 
-	var nBytes int
+		if opt.Palette == nil {
+			opt.Palette = &PalOrig
+		}
 
-	fnWrite := func(str string) bool {
+		opt.TrueColor, opt.Xterm256 = false, false
+		if ColorLimit(opt.Colors) != COLORS_8 {
+			opt.Colors = COLORS_16
+		}
 
-		nBytes += len(str)
+		fmt.Fprint(iWri, opt.Palette.OSC4())
 
-		// LINE-LENGTH LIMITATION
-		if (nRowBytes > 0) && (nBytes > nRowBytes) {
-			return true
+		if !opt.KeepPalette {
+			defer fmt.Fprint(iWri, OSC_PALETTE_RESTORE)
 		}
+	}
 
-		fmt.Fprint(iWri, str)
-		return false
+	// TRAILING BLANK ROWS
+	nRows := len(gr.grid)
+	for opt.Trim && (nRows > 0) {
+
+		if sKept, _ := trimRow(gr.grid[nRows-1], &opt); len(sKept) > 0 {
+			break
+		}
+		nRows--
 	}
 
-	for nRow, sRow := range gr.grid {
+	for nRow, sRow := range gr.grid[:nRows] {
 
-		// RESET PER-LINE BYTE COUNT
-		nBytes = 0
-		fnWrite(STR_CLEAR)
+		szErase := ""
+		if opt.Trim {
+			sRow, szErase = trimRow(sRow, &opt)
+		}
 
-		if bDebug {
-			lineNum := fmt.Sprintf("%5d: ", nRow+1)
-			fnWrite(lineNum)
+		szPrefix := ""
+		if opt.Debug {
+			szPrefix = fmt.Sprintf("%5d: ", nRow+1)
 		}
 
-		// RENDER CELLS IN ROW
-		brushPrev := SGR{}
-		for ix_cell, cell := range sRow {
+		var sLines []string
+
+		switch opt.Overflow {
+
+		// SHORTER CODES, THEN FEWER COLORS, UNTIL THE ROW FITS
+		case OVERFLOW_FIT:
+
+			for _, nColors := range []int{opt.Colors, COLORS_256, COLORS_16} {
+
+				// PALETTE ALREADY FIXED AT 16 COLORS
+				optFit := opt
+				if nColors != opt.Colors {
 
-			// WRITE SGR CODE ON CHANGE
-			// ALWAYS WRITE FOR NEW ROW (FOR BG/FG COLOR OVERRIDE)
-			if escTemp := cell.Brush.ToEsc(&brushPrev, ix_cell > 0, bXterm256, bFakeEsc); len(escTemp) > 0 {
-				if fnWrite(escTemp) {
+					nLimit := ColorLimit(opt.Colors)
+					if opt.SetPalette || ((nLimit != 0) && (nLimit <= nColors)) {
+						continue
+					}
+					optFit.Colors = nColors
+				}
+
+				var bCut bool
+				if sLines, bCut = gr.printRow(sRow, szPrefix, szErase, &optFit, true); !bCut {
 					break
 				}
 			}
 
-			brushPrev = cell.Brush
+		default:
+			sLines, _ = gr.printRow(sRow, szPrefix, szErase, &opt, false)
+		}
 
-			// DEFAULT PAINT CHAR
-			if cell.Char == 0 {
-				cell.Char = ' '
-			}
+		for _, szLine := range sLines {
+			fmt.Fprint(iWri, szLine, "\n")
+		}
+	}
+}
+
+/*
+	Output lines for `sRow`, each within .MaxBytes of `pOpt` (if set), without
+	splitting escapes or characters, colors reset at the end of each.
+	Cells past the budget continue on another line with OVERFLOW_WRAP, otherwise
+	they're dropped (`bCut` is set).  `bCompact` picks the shorter of diff &
+	reset for each SGR change, and keeps the FG of blanks.
+	`szErase` (see `trimRow`) goes before the final reset, unless cut.
+*/
+func (gr *Grid) printRow(sRow GridRow, szPrefix, szErase string, pOpt *RenderOpts, bCompact bool) (sLines []string, bCut bool) {
+
+	szClear := "\x1b[0m"
+	if (pOpt.Caps != nil) && pOpt.Caps.NoSGR() {
+		szClear = ""
+	}
+
+	szClose := szClear
+	if pOpt.Debug {
+		szClose += "|"
+	}
+
+	// NOTHING TO RESET
+	if (len(sRow) == 0) && (len(szErase) == 0) {
+		return []string{szPrefix + strings.TrimPrefix(szClose, szClear)}, false
+	}
 
-			if fnWrite(string(cell.Char)) {
+	szLine := szClear + szPrefix
+	bFirst := true
+
+	brushPrev := SGR{}
+	for ix := 0; ix < len(sRow); ix++ {
+
+		cell := sRow[ix]
+
+		// DEFAULT PAINT CHAR
+		if cell.Char == 0 {
+			cell.Char = ' '
+		}
+
+		// WRITE SGR CODE ON CHANGE
+		// ALWAYS WRITE FOR NEW LINE (FOR BG/FG COLOR OVERRIDE)
+		var escTemp string
+		if bCompact {
+			escTemp = compactEsc(&cell, &brushPrev, !bFirst, pOpt)
+		} else {
+			escTemp = cell.Brush.ToEsc(&brushPrev, !bFirst, pOpt)
+		}
+
+		szCell := escTemp + string(cell.Char)
+
+		// LINE-LENGTH LIMITATION
+		if (pOpt.MaxBytes > 0) && (len(szLine)+len(szCell)+len(szErase)+len(szClose) > pOpt.MaxBytes) {
+
+			bCut = true
+			if (pOpt.Overflow != OVERFLOW_WRAP) || bFirst {
 				break
 			}
-		}
 
-		fnWrite(STR_CLEAR)
+			// CONTINUE ON NEXT LINE
+			sLines = append(sLines, szLine+szClose)
+			szLine, bFirst = szClear, true
+			ix--
+			continue
+		}
 
-		if bDebug {
-			fnWrite("|")
+		szLine += szCell
+		bFirst = false
+		if bCompact {
+			brushPrev = compactBrush(&cell, &brushPrev)
+		} else {
+			brushPrev = cell.Brush
 		}
+	}
+
+	if !bCut {
+		szLine += szErase
+	}
+
+	sLines = append(sLines, szLine+szClose)
+	return
+}
+
+// ERASE TO END OF LINE, IN THE CURRENT BACKGROUND COLOR
+const ESC_ERASE_EOL = "\x1b[K"
+
+/*
+	`sRow` without its trailing blanks that show the terminal's own background
+	(unwritten, or 49).  With .EraseEOL, past the last visible cell, a run of
+	blanks in its background becomes ESC_ERASE_EOL (`szErase`) when that's
+	shorter: only right on terminals with back-color-erase (BCE).
+	Blanks with underline, inverse or strikethrough are always kept.
+*/
+func trimRow(sRow GridRow, pOpt *RenderOpts) (sKept GridRow, szErase string) {
+
+	// ERASING WOULD PAINT THE BARE CELLS
+	ixEnd := len(sRow)
+	if sKept = trimBare(sRow); len(sKept) < ixEnd {
+		return sKept, ""
+	}
+
+	if !pOpt.EraseEOL || pOpt.FakeEsc || ((pOpt.Caps != nil) && pOpt.Caps.NoSGR()) {
+		return sRow, ""
+	}
+
+	// BLANKS IN THE BACKGROUND OF THE CELL BEFORE THEM
+	ixKeep := ixEnd
+	for (ixKeep > 1) && sRow[ixKeep-1].IsBlank() &&
+		IaEqual(sRow[ixKeep-1].Brush.GetColor(CIX_BG, nil), sRow[ixKeep-2].Brush.GetColor(CIX_BG, nil)) {
+		ixKeep--
+	}
+
+	bInverse := (ixKeep > 0) && ((sRow[ixKeep-1].Brush.Flags & SGR_INVERSE) != 0)
+	if bInverse || (len(sRow)-ixKeep <= len(ESC_ERASE_EOL)) {
+		return sRow, ""
+	}
 
-		fnWrite("\n")
+	return sRow[:ixKeep], ESC_ERASE_EOL
+}
+
+// ATTRIBUTES THAT SHOW ON BLANKS
+const SGR_ON_BLANKS uint32 = SGR_UNDERLINE | SGR_INVERSE | SGR_STRIKETHROUGH
+
+/*
+	Brush that draws `pCell` like its own, changing as little of `pPrev` as
+	possible: blanks keep the FG & FG-only attributes of `pPrev`
+*/
+func compactBrush(pCell *GridCell, pPrev *SGR) SGR {
+
+	S := pCell.Brush
+	if (pCell.Char != ' ') || ((S.Flags & SGR_ON_BLANKS) != 0) {
+		return S
+	}
+
+	S.Flags = pPrev.Flags &^ SGR_ON_BLANKS
+	S.Color[CIX_FG] = pPrev.Color[CIX_FG]
+	return S
+}
+
+/*
+	Shorter of diff & reset-then-set (ESC[0;...m) from `pPrev` to `compactBrush`
+*/
+func compactEsc(pCell *GridCell, pPrev *SGR, bAsDiff bool, pOpt *RenderOpts) string {
+
+	S := compactBrush(pCell, pPrev)
+	szEsc := S.ToEsc(pPrev, bAsDiff, pOpt)
+
+	if !bAsDiff || (len(szEsc) == 0) || pOpt.FakeEsc {
+		return szEsc
+	}
+
+	szReset := "\x1b[0m"
+	if szSet := S.ToEsc(pPrev, false, pOpt); len(szSet) > 0 {
+		szReset = "\x1b[0;" + strings.TrimPrefix(szSet, "\x1b[")
+	}
+
+	if len(szReset) < len(szEsc) {
+		return szReset
 	}
+
+	return szEsc
+}
+
+/*
+	True for a space (or nothing) without attributes that show on blanks
+*/
+func (gc *GridCell) IsBlank() bool {
+	return ((gc.Char == 0) || (gc.Char == ' ')) && ((gc.Brush.Flags & SGR_ON_BLANKS) == 0)
+}
+
+/*
+	`sRow` without its trailing blanks in the terminal's own background
+	(unwritten, or 49).  Spaces drawn in the default BG (40) are kept.
+*/
+func trimBare(sRow GridRow) GridRow {
+
+	ixEnd := len(sRow)
+	for ixEnd > 0 {
+
+		pC := &sRow[ixEnd-1]
+		sBG := pC.Brush.Color[CIX_BG]
+
+		bBare := (!pC.Written && (len(sBG) == 0)) || IsTermDefault(sBG)
+		if !pC.IsBlank() || !bBare {
+			break
+		}
+		ixEnd--
+	}
+
+	return sRow[:ixEnd]
 }
@@ -0,0 +1,292 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var rxSGR = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+func TestRowBudget(t *testing.T) {
+
+	bsIn, E := ioutil.ReadFile(TEST_DIR + "/fruit.ans")
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	const MAX_BYTES = 200
+
+	for _, szMode := range OverflowModes {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 80, MaxBytes: MAX_BYTES, Overflow: szMode, TrueColor: true, Writer: &buf}
+		if E = UM.Encode(bytes.NewReader(bsIn)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		for ix, szLine := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+
+			if len(szLine) > MAX_BYTES {
+				t.Errorf("%s: LINE %d IS %d BYTES", szMode, ix+1, len(szLine))
+			}
+
+			// COLORS CLOSED, NOTHING SPLIT
+			if !strings.HasSuffix(szLine, "\x1b[0m") || !strings.HasPrefix(szLine, "\x1b[0m") {
+				t.Errorf("%s: LINE %d NOT RESET: %q", szMode, ix+1, szLine)
+			}
+
+			if szBare := rxSGR.ReplaceAllString(szLine, ""); strings.Contains(szBare, "\x1b") || !utf8.ValidString(szBare) {
+				t.Errorf("%s: LINE %d SPLIT: %q", szMode, ix+1, szLine)
+			}
+		}
+	}
+}
+
+func TestTrim(t *testing.T) {
+
+	sTests := []struct {
+		In       string
+		EraseEOL bool
+		Expect   string
+	}{
+		// UNWRITTEN CELLS & ROWS DROPPED
+		{"\x1b[41mX\r\n\r\n\r\n", false, "\x1b[0m\x1b[37;41mX\x1b[0m\n"},
+		// DRAWN SPACES KEPT, BLACK IS NOT THE TERMINAL'S BACKGROUND
+		{"\x1b[41mX\x1b[0m   \r\n", false, "\x1b[0m\x1b[37;41mX\x1b[40m   \x1b[0m\n"},
+		// BACKGROUND TO END OF LINE ERASED IN ITS COLOR, IF ASKED (BCE)
+		{"\x1b[44mAB                  \x1b[0m", true, "\x1b[0m\x1b[37;44mAB\x1b[K\x1b[0m\n"},
+		{"\x1b[44mAB                  \x1b[0m", false, "\x1b[0m\x1b[37;44mAB                  \x1b[0m\n"},
+		// COLORED BLANKS BEFORE UNWRITTEN ONES KEPT
+		{"\x1b[42m          ", true, "\x1b[0m\x1b[37;42m          \x1b[0m\n"},
+		// UNDERLINED BLANKS KEPT
+		{"hi\x1b[4m  ", true, "\x1b[0m\x1b[37;40mhi\x1b[4m  \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 20, Trim: true, EraseEOL: T.EraseEOL, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestClearBG(t *testing.T) {
+
+	sTests := []struct {
+		In          string
+		Transparent string
+		Expect      string
+	}{
+		// DRAWN BLUE SPACES KEEP THEIR BG
+		{"\x1b[44m    \x1b[0mX", "none", "\x1b[0m\x1b[37;44m    \x1b[49mX \x1b[0m\n"},
+		// BLACK BG CLEARED
+		{"\x1b[40mA\x1b[44mB", "0", "\x1b[0m\x1b[37;49mA\x1b[44mB\x1b[49m    \x1b[0m\n"},
+		// INVERSE LEFT ALONE
+		{"\x1b[7mA", "none", "\x1b[0m\x1b[7;37;40mA\x1b[27;49m     \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		sClr, E := ParseColorList(T.Transparent, &PalDefault)
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 6, Transparent: sClr, Writer: &buf}
+		if E = UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestBrighten(t *testing.T) {
+
+	sTests := []struct {
+		In         string
+		BoldBright bool
+		ICEColors  bool
+		Expect     string
+	}{
+		// BOLD 30-37 & DEFAULT FG TO 90-97; 256-COLOR & BRIGHT LEFT ALONE
+		{"\x1b[1;31mA\x1b[0;1mB\x1b[0;1;38;5;1mC\x1b[0;1;91mD", true, false, "\x1b[0m\x1b[1;91;40mA\x1b[97mB\x1b[38;5;1mC\x1b[91mD\x1b[22;37m  \x1b[0m\n"},
+		// BLINK 40-47 & DEFAULT BG TO 100-107, BLINK DROPPED EVERYWHERE
+		{"\x1b[5;41mA\x1b[0;5mB\x1b[0;5;48;5;1mC\x1b[0;5;101mD", false, true, "\x1b[0m\x1b[37;101mA\x1b[100mB\x1b[48;5;1mC\x1b[101mD\x1b[40m  \x1b[0m\n"},
+		// NEITHER: PASSED THROUGH
+		{"\x1b[1;31mA\x1b[0;5;41mB", false, false, "\x1b[0m\x1b[1;31;40mA\x1b[22;5;37;41mB\x1b[25;40m    \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 6, BoldBright: T.BoldBright, ICEColors: T.ICEColors, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestResolveAttrs(t *testing.T) {
+
+	sTests := []struct {
+		In     string
+		Expect string
+	}{
+		// INVERSE SWAPPED
+		{"\x1b[7;31;44mA", "\x1b[0m\x1b[34;41mA\x1b[37;40m     \x1b[0m\n"},
+		// DEFAULTS SWAPPED TOO
+		{"\x1b[7mB", "\x1b[0m\x1b[30;47mB\x1b[37;40m     \x1b[0m\n"},
+		// BOLD BRIGHTENS THE FG BEFORE IT BECOMES THE BG
+		{"\x1b[1;7;32mC", "\x1b[0m\x1b[30;102mC\x1b[37;40m     \x1b[0m\n"},
+		// CONCEAL: FG = BG
+		{"\x1b[8;33;44mD", "\x1b[0m\x1b[34;44mD\x1b[37;40m     \x1b[0m\n"},
+		// 256-COLOR KEPT, RE-TARGETED
+		{"\x1b[7;38;5;200mE", "\x1b[0m\x1b[30;48;5;200mE\x1b[37;40m     \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 6, ResolveAttrs: true, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestLightBG(t *testing.T) {
+
+	const IN = "\x1b[37mA\x1b[0;30;47mB\x1b[0;97mC\x1b[0;34mD\x1b[0m\xb0\xb1\xb2"
+
+	sTests := []struct {
+		TrueColor bool
+		Expect    string
+	}{
+		// GRAYS TRADE PLACES, BLUE BRIGHTENED, SHADES DARK ON LIGHT
+		{false, "\x1b[0m\x1b[90;107mA\x1b[97;100mB\x1b[30;107mC\x1b[94mD\x1b[90m░▒▓ \x1b[0m\n"},
+		{true, "\x1b[0m\x1b[38;2;85;85;85;48;2;255;255;255mA\x1b[38;2;255;255;255;48;2;85;85;85mB\x1b[38;2;0;0;0;48;2;255;255;255mC\x1b[38;2;85;85;255mD\x1b[38;2;85;85;85m░▒▓ \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 8, LightBG: true, TrueColor: T.TrueColor, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(IN)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("TRUECOLOR %v: GOT %q, EXPECT %q", T.TrueColor, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestGrayMono(t *testing.T) {
+
+	const GRAY_IN = "\x1b[31mA\x1b[0;44mB\x1b[0;38;2;0;255;0mC"
+
+	sTests := []struct {
+		In     string
+		UM     UTF8Marshaller
+		Expect string
+	}{
+		// LUMINANCE, IN 24-BIT & 256-COLOR GRAYS
+		{GRAY_IN, UTF8Marshaller{Grayscale: true, TrueColor: true}, "\x1b[0m\x1b[38;2;89;89;89;48;2;0;0;0mA\x1b[38;2;170;170;170;48;2;54;54;54mB\x1b[38;2;211;211;211;48;2;0;0;0mC\x1b[38;2;170;170;170m     \x1b[0m\n"},
+		{GRAY_IN, UTF8Marshaller{Grayscale: true, Translate2Xterm256: true}, "\x1b[0m\x1b[38;5;240;48;5;16mA\x1b[38;5;248;48;5;237mB\x1b[38;5;252;48;5;16mC\x1b[38;5;248m     \x1b[0m\n"},
+		// SHADES BY LUMINANCE, HALF BLOCKS FLIPPED LIGHT ON DARK, TEXT KEPT
+		{"\x1b[31;44mA\x1b[0;47m \x1b[0;97;40m\xdb\x1b[0;30;47m\xdf\x1b[0;34;44mX", UTF8Marshaller{Monochrome: true}, "\x1b[0m\x1b[37;40mA▓█▄░   \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := T.UM
+		UM.Width, UM.Writer = 8, &buf
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestXterm256(t *testing.T) {
+
+	sTests := []struct {
+		In     string
+		Pal    *Palette
+		Expect string
+	}{
+		// CLASSIC COLORS TRANSLATED
+		{"\x1b[1;31;44mX", nil, "\x1b[0m\x1b[1;38;5;203;48;5;19mX\x1b[22;38;5;248;48;5;16m   \x1b[0m\n"},
+		// 256 & 24-BIT KEPT WHOLE, ONLY DOWNSAMPLED
+		{"\x1b[38;5;31;48;2;100;40;45mX", nil, "\x1b[0m\x1b[38;5;31;48;5;52mX\x1b[38;5;248;48;5;16m   \x1b[0m\n"},
+		{"\x1b[38;5;31;48;2;100;40;45mX", &PalVGA, "\x1b[0m\x1b[38;5;31;48;5;52mX\x1b[38;5;248;48;5;16m   \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 4, Translate2Xterm256: true, Palette: T.Pal, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
+
+func TestSetPalette(t *testing.T) {
+
+	// 24-BIT, 256 & CLASSIC ALL COME OUT IN 16 COLORS
+	const IN = "\x1b[38;2;170;0;0;48;5;19mX\x1b[0;1;32mY"
+	const BODY = "\x1b[0m\x1b[31;44mX\x1b[1;32;40mY\x1b[22;37m  \x1b[0m\n"
+
+	for _, bKeep := range []bool{false, true} {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{
+			Width: 4, Palette: &PalVGA, SetPalette: true, KeepPalette: bKeep,
+			TrueColor: true, Translate2Xterm256: true, Writer: &buf,
+		}
+
+		if E := UM.Encode(strings.NewReader(IN)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		szExpect := PalVGA.OSC4() + BODY
+		if !bKeep {
+			szExpect += OSC_PALETTE_RESTORE
+		}
+
+		if buf.String() != szExpect {
+			t.Errorf("KEEP %v: GOT %q, EXPECT %q", bKeep, buf.String(), szExpect)
+		}
+	}
+}
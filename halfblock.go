@@ -0,0 +1,131 @@
+package ansiart2utf8
+
+import (
+	"image/color"
+	"math"
+)
+
+const (
+	HALF_UPPER = '▀'
+	HALF_LOWER = '▄'
+)
+
+/*
+	Re-renders the grid `nCols` wide, 2 pixels per cell in ▀ half blocks
+	(top as FG, bottom as BG) in 24-bit color: rasterized through `pal` &
+	the 8x16 font, then box-filtered in linear light to square pixels.
+	Pixels mostly of terminal default BG (49) stay terminal default.
+	A grid no wider than `nCols` is returned as is.
+*/
+func (gr *Grid) HalfBlocks(pal *Palette, nCols int) Grid {
+
+	if (nCols < 1) || (gr.Width() <= nCols) {
+		return gr.Clone()
+	}
+
+	pImg := gr.Rasterize(pal)
+	nSrcW, nSrcH := pImg.Bounds().Dx(), pImg.Bounds().Dy()
+
+	// SOURCE PIXELS PER OUTPUT PIXEL, EACH WAY
+	fStep := float64(nSrcW) / float64(nCols)
+	nRows := int(math.Ceil(float64(nSrcH) / fStep / 2))
+
+	var sLinear [256]float64
+	for ix := range sLinear {
+
+		f := float64(ix) / 255
+		if f <= 0.04045 {
+			sLinear[ix] = f / 12.92
+		} else {
+			sLinear[ix] = math.Pow((f+0.055)/1.055, 2.4)
+		}
+	}
+
+	fnGamma := func(f float64) uint8 {
+
+		if f <= 0.0031308 {
+			f *= 12.92
+		} else {
+			f = (1.055 * math.Pow(f, 1/2.4)) - 0.055
+		}
+		return uint8(math.Round(math.Max(0, math.Min(1, f)) * 255))
+	}
+
+	// AVERAGE OF OUTPUT PIXEL (x, y), TRANSPARENT IF MOSTLY SO
+	fnPixel := func(x, y int) color.RGBA {
+
+		fX0, fY0 := float64(x)*fStep, float64(y)*fStep
+		fX1, fY1 := fX0+fStep, fY0+fStep
+
+		var fR, fG, fB, fOpaque, fTotal float64
+		for sy := int(fY0); (sy < nSrcH) && (float64(sy) < fY1); sy++ {
+
+			fH := math.Min(fY1, float64(sy+1)) - math.Max(fY0, float64(sy))
+			for sx := int(fX0); (sx < nSrcW) && (float64(sx) < fX1); sx++ {
+
+				fW := (math.Min(fX1, float64(sx+1)) - math.Max(fX0, float64(sx))) * fH
+				fTotal += fW
+
+				C := pImg.RGBAAt(sx, sy)
+				if C.A == 0 {
+					continue
+				}
+
+				fR += sLinear[C.R] * fW
+				fG += sLinear[C.G] * fW
+				fB += sLinear[C.B] * fW
+				fOpaque += fW
+			}
+		}
+
+		if (fOpaque == 0) || (fOpaque < fTotal/2) {
+			return color.RGBA{}
+		}
+
+		return color.RGBA{fnGamma(fR / fOpaque), fnGamma(fG / fOpaque), fnGamma(fB / fOpaque), 255}
+	}
+
+	fnClr := func(C color.RGBA, CIX int) []int {
+
+		if C.A == 0 {
+			return []int{DEFAULT_BG_TERM}
+		}
+
+		nExt := 38
+		if CIX == CIX_BG {
+			nExt = 48
+		}
+		return []int{nExt, 2, int(C.R), int(C.G), int(C.B)}
+	}
+
+	G, _ := NewGrid(uint(nCols))
+	G.Touch(nRows)
+
+	for ixRow := 0; ixRow < nRows; ixRow++ {
+		for ixCol := 0; ixCol < nCols; ixCol++ {
+
+			T, B := fnPixel(ixCol, ixRow*2), fnPixel(ixCol, (ixRow*2)+1)
+			pC := &G.grid[ixRow][ixCol]
+			pC.Written = true
+
+			switch {
+
+			// BOTH HALVES ALIKE: A BLANK IN THEIR COLOR
+			case T == B:
+				pC.Brush.Color[CIX_BG] = fnClr(B, CIX_BG)
+
+			case T.A == 0:
+				pC.Char = HALF_LOWER
+				pC.Brush.Color[CIX_FG] = fnClr(B, CIX_FG)
+				pC.Brush.Color[CIX_BG] = fnClr(T, CIX_BG)
+
+			default:
+				pC.Char = HALF_UPPER
+				pC.Brush.Color[CIX_FG] = fnClr(T, CIX_FG)
+				pC.Brush.Color[CIX_BG] = fnClr(B, CIX_BG)
+			}
+		}
+	}
+
+	return G
+}
@@ -0,0 +1,36 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestHalfBlocks(t *testing.T) {
+
+	sTests := []struct {
+		Thumb  uint
+		In     string
+		Expect string
+	}{
+		// 2x2 CELLS TO ONE, BOTTOM FROM THE (BLACK) 2ND ROW
+		{2, "\x1b[41m  \x1b[44m  ", "\x1b[0m\x1b[38;2;170;0;0;48;2;0;0;0m▀\x1b[38;2;0;0;170m▀\x1b[0m\n"},
+		// A QUARTER RED, AVERAGED IN LINEAR LIGHT (NOT 43); NOTHING BELOW THE ART
+		{1, "\x1b[41m  \x1b[40m  ", "\x1b[0m\x1b[38;2;89;0;0;49m▀\x1b[0m\n"},
+		// NOT WIDER THAN THE THUMBNAIL: UNCHANGED
+		{4, "\x1b[41mAB", "\x1b[0m\x1b[37;41mAB\x1b[40m  \x1b[0m\n"},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 4, Thumbnail: T.Thumb, Writer: &buf}
+		if E := UM.Encode(strings.NewReader(T.In)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, buf.String(), T.Expect)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package ansiart2utf8
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	ANSI INTERPRETER STATE, FED ONE BYTE AT A TIME.
+	PRE-RENDERS TO .Grid (MOTION ESCAPES, COLOR CHANGES, ETC)
+*/
+type Interpreter struct {
+	Grid Grid
+
+	bEsc     bool
+	bDone    bool
+	escCur   EscCode
+	sgrCur   SGR
+	sgrSaved SGR
+	posCur   GridPos
+	posSaved GridPos
+	ixByte   int
+	fnDebug  DebugFunc
+
+	// TERMINAL CAPTURE MODE, SEE term.go
+	bTerm        bool
+	bWrapPending bool
+	nRows        int
+	termEsc      int
+	bsRune       []byte
+}
+
+func (M UTF8Marshaller) NewInterpreter() (pI *Interpreter, E error) {
+
+	G, E := NewGrid(M.Width)
+	if E != nil {
+		return
+	}
+
+	pI = &Interpreter{
+		Grid:     G,
+		posCur:   NewPos(),
+		posSaved: NewPos(),
+		ixByte:   -1,
+		fnDebug:  M.Debug,
+	}
+
+	return
+}
+
+/*
+	True once a NULL or SUB (start of SAUCE) has been seen,
+	further input is ignored
+*/
+func (pI *Interpreter) Done() bool {
+	return pI.bDone
+}
+
+// NUMBER OF BYTES CONSUMED
+func (pI *Interpreter) Count() int {
+	return pI.ixByte + 1
+}
+
+func (pI *Interpreter) debug(v ...interface{}) (int, error) {
+
+	if pI.fnDebug != nil {
+		v = append(v, fmt.Sprintf("at index %d", pI.ixByte))
+		return pI.fnDebug(v...)
+	}
+
+	return 0, nil
+}
+
+/*
+	Implements io.Writer
+*/
+func (pI *Interpreter) Write(p []byte) (int, error) {
+
+	for ix := range p {
+		if E := pI.WriteByte(p[ix]); E != nil {
+			return ix, E
+		}
+	}
+
+	return len(p), nil
+}
+
+/*
+	Implements io.ByteWriter
+*/
+func (pI *Interpreter) WriteByte(chr byte) (E error) {
+
+	if pI.bDone {
+		return nil
+	}
+
+	pI.ixByte += 1
+	defer func() {
+
+		if E != nil {
+			E = fmt.Errorf("%s, at index %d", E.Error(), pI.ixByte)
+		}
+	}()
+
+	if pI.bTerm {
+		return pI.writeTerm(chr)
+	}
+
+	// TODO: try to extract dims from sauce
+
+	switch chr {
+
+	// TODO: break at ^ZSAUCE00 (^Z is 26 dec, 0x1A hex)
+	// STOP ON NULL & SAUCE
+	case 0, 26:
+		pI.bDone = true
+		return nil
+	}
+
+	if chr == CHR_CR {
+
+		pI.posCur.X = 1
+		return nil
+
+	} else if chr == CHR_LF {
+
+		// EXTEND ROW
+		pI.posCur.Y += 1
+		pI.Grid.Touch(pI.posCur.Y)
+		return nil
+
+	} else if chr == CHR_ESCAPE {
+
+		// BEGIN ESCAPE CODE
+		pI.bEsc = true
+		pI.escCur.Reset()
+		return nil
+
+		// HANDLE ESCAPE CODE SEQUENCE
+	} else if pI.bEsc {
+
+		// ESCAPE CODE TERMINATING CHARS:
+		if strings.IndexByte(SGR_TERMINATORS, chr) == -1 {
+
+			// APPEND COMPONENT OF ESCAPE SEQUENCE
+			pI.escCur.Params += string(chr)
+			return nil
+		}
+
+		// EXIT ESCAPE CODE FSM SUCCESSFULLY ON TERMINATING 'm' CHARACTER
+
+		pI.bEsc = false
+		pI.escCur.Code = rune(chr)
+
+		if pI.escCur.Validate() {
+			return pI.execEsc()
+		}
+
+		pI.debug("INVALID CODE: ", pI.escCur.Debug())
+		return nil
+	}
+
+	// HANDLE WRITABLE CHARACTERS OUTSIDE OF ESCAPE MODE
+	if e2 := pI.Grid.Put(pI.posCur, Array437[chr], pI.sgrCur); e2 != nil {
+		pI.debug(e2)
+	}
+
+	pI.Grid.Inc(&pI.posCur, 1)
+	return nil
+}
+
+/*
+	Applies validated escape code in .escCur
+*/
+func (pI *Interpreter) execEsc() error {
+
+	// ONLY RESTORE SGR ESCAPE CODES
+	switch pI.escCur.Code {
+
+	case 'm':
+
+		if e2 := pI.sgrCur.MergeCodes(pI.escCur.SubParams); e2 != nil {
+			return fmt.Errorf("SGR ERROR %s", e2.Error())
+		}
+
+	// UP
+	case 'A':
+
+		pI.Grid.IncClamp(&pI.posCur, 0, -int(pI.escCur.SubParams[0]))
+
+	// DOWN
+	case 'B':
+
+		pI.Grid.IncClamp(&pI.posCur, 0, int(pI.escCur.SubParams[0]))
+
+	// FORWARD
+	case 'C':
+
+		pI.Grid.IncClamp(&pI.posCur, int(pI.escCur.SubParams[0]), 0)
+		// pI.Grid.Touch(pI.posCur.Y)
+
+	// BACK
+	case 'D':
+
+		pI.Grid.IncClamp(&pI.posCur, -int(pI.escCur.SubParams[0]), 0)
+
+	// NOTE: NOT ANSI.SYS
+	case 'E', 'F', 'G':
+		// E: beginning on line, n lines down
+		// F: beginning on line, n lines up
+		// G: cursor to column n
+
+	// TO X,Y
+	case 'H', 'f':
+
+		pI.posCur.Y = int(pI.escCur.SubParams[0])
+		pI.posCur.X = int(pI.escCur.SubParams[1])
+		pI.Grid.Touch(pI.posCur.Y)
+
+	case 'J':
+
+		switch pI.escCur.SubParams[0] {
+
+		// clear from cursor to end of screen
+		case 0:
+			pI.Grid.ClearFromPosToEnd(pI.posCur)
+
+		// clear from cursor to beginning of screen
+		case 1:
+			pI.Grid.ClearFromPosToBegin(pI.posCur)
+
+		// clear entire screen, move cursor to upper-left
+		case 2:
+			pI.posCur.X, pI.posCur.Y = 1, 1
+			pI.Grid.ClearFromPosToEnd(pI.posCur)
+
+		// clear entire screen, reset scrollback buffer
+		case 3:
+			pI.Grid.ClearFromPosToEnd(GridPos{1, 1})
+		}
+
+	case 'K':
+
+		switch pI.escCur.SubParams[0] {
+
+		// clear from cursor to end of line
+		case 0:
+			pI.Grid.ClearLine(pI.posCur, false)
+
+		// clear from cursor to beginning of line
+		case 1:
+			pI.Grid.ClearLine(pI.posCur, true)
+
+		// clear entire line
+		case 2:
+			pI.Grid.ClearLine(GridPos{X: 1, Y: pI.posCur.Y}, false)
+		}
+
+	// NO-OP: NOT ANSI.SYS
+	case 'S', 'T':
+		// S: scroll page up by n lines
+		// T: scroll page down by n lines
+
+	// SAVE CURSOR POS & SGR
+	case 's':
+
+		pI.posSaved = pI.posCur
+		pI.sgrSaved = pI.sgrCur
+
+	// RESTORE CURSOR POS & SGR
+	case 'u':
+
+		pI.posCur = pI.posSaved
+		pI.sgrCur = pI.sgrSaved
+
+	default:
+
+		return fmt.Errorf("UNHANDLED CODE %s", pI.escCur.Debug())
+	}
+
+	return nil
+}
@@ -0,0 +1,401 @@
+package ansiart2utf8
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// MARKS A GRID DUMP, FOR INPUT DETECTION
+const JSON_GRID_ID = "ansiart2utf8-grid"
+
+// LARGEST GRID READ FROM JSON
+const (
+	JSON_MAX_WIDTH  = 4096
+	JSON_MAX_HEIGHT = 65535
+	JSON_MAX_CELLS  = 1 << 22
+)
+
+/*
+	Grid dump, see `Grid.PrintJSON`
+*/
+type JSONGrid struct {
+	Format  string       `json:"format"`
+	Width   int          `json:"width"`
+	Height  int          `json:"height"`
+	Palette []string     `json:"palette"` // 16 x #RRGGBB
+	Sauce   *Sauce       `json:"sauce,omitempty"`
+	Rows    [][]JSONCell `json:"rows,omitempty"`
+}
+
+type JSONCell struct {
+	Char  string    `json:"char"`
+	Byte  *int      `json:"byte,omitempty"` // CP437, IF THERE IS ONE
+	FG    JSONColor `json:"fg"`
+	BG    JSONColor `json:"bg"`
+	Attrs []string  `json:"attrs,omitempty"`
+	Run   int       `json:"run,omitempty"` // REPEATS, IN THE RUN-LENGTH VARIANT
+
+	// NEVER DRAWN ON, SEE `GridCell.Written`
+	Unwritten bool `json:"unwritten,omitempty"`
+}
+
+type JSONColor struct {
+	Type  string `json:"type"`            // JSON_CLR_*
+	Index *int   `json:"index,omitempty"` // JSON_CLR_CLASSIC: 0-15, JSON_CLR_XTERM256: 0-255
+	RGB   string `json:"rgb,omitempty"`   // AS SHOWN, PER PALETTE; NONE FOR JSON_CLR_TERMINAL
+}
+
+// JSONColor TYPES
+const (
+	JSON_CLR_DEFAULT  = "default"  // UNSET (37 / 40)
+	JSON_CLR_TERMINAL = "terminal" // TERMINAL'S OWN (39 / 49)
+	JSON_CLR_CLASSIC  = "classic"  // 30-37 & 90-97 / 40-47 & 100-107
+	JSON_CLR_XTERM256 = "xterm256" // 38;5;n / 48;5;n
+	JSON_CLR_RGB      = "rgb"      // 38;2;r;g;b / 48;2;r;g;b
+)
+
+// ATTRIBUTE NAMES, IN SGR ORDER
+var sgrNames = []struct {
+	Flag uint32
+	Name string
+}{
+	{SGR_BOLD, "bold"},
+	{SGR_FAINT, "faint"},
+	{SGR_ITALIC, "italic"},
+	{SGR_UNDERLINE, "underline"},
+	{SGR_BLNK_SLOW, "blink"},
+	{SGR_BLNK_FAST, "rapidblink"},
+	{SGR_INVERSE, "inverse"},
+	{SGR_CONCEAL, "conceal"},
+	{SGR_STRIKETHROUGH, "strike"},
+}
+
+/*
+	Writes the grid as JSON: dimensions, `pal` (PalDefault if nil), `pSauce`
+	(if not nil), then one line per row of cells.  With `bRLE`, runs of equal
+	cells are written once, with .Run.  See `ParseJSONGrid` for the reverse.
+*/
+func (gr *Grid) PrintJSON(iWri io.Writer, pal *Palette, pSauce *Sauce, bRLE bool) error {
+
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	doc := JSONGrid{
+		Format:  JSON_GRID_ID,
+		Width:   gr.Width(),
+		Height:  gr.Height(),
+		Palette: make([]string, len(pal)),
+		Sauce:   pSauce,
+	}
+
+	for ix, C := range pal {
+		doc.Palette[ix] = fmt.Sprintf("#%02x%02x%02x", C.R, C.G, C.B)
+	}
+
+	bsHead, E := jsonMarshal(doc)
+	if E != nil {
+		return E
+	}
+
+	pWri := bufio.NewWriter(iWri)
+
+	// ROWS AFTER THE HEADER FIELDS, ONE PER LINE
+	pWri.Write(bsHead[:len(bsHead)-1])
+	pWri.WriteString(`,"rows":[`)
+
+	for ixRow, sRow := range gr.grid {
+
+		sCells := []JSONCell{}
+		for ix := 0; ix < len(sRow); ix++ {
+
+			nRun := 1
+			for bRLE && (ix+nRun < len(sRow)) && sRow[ix].Equal(&sRow[ix+nRun]) &&
+				(sRow[ix].Written == sRow[ix+nRun].Written) {
+				nRun++
+			}
+
+			C := jsonCell(&sRow[ix], pal)
+			if nRun > 1 {
+				C.Run = nRun
+			}
+
+			sCells = append(sCells, C)
+			ix += nRun - 1
+		}
+
+		bsRow, E := jsonMarshal(sCells)
+		if E != nil {
+			return E
+		}
+
+		if ixRow > 0 {
+			pWri.WriteString(",")
+		}
+
+		pWri.WriteString("\n")
+		pWri.Write(bsRow)
+	}
+
+	pWri.WriteString("\n]}")
+	return pWri.Flush()
+}
+
+/*
+	json.Marshal, without escaping <, > & & (ART ISN'T HTML)
+*/
+func jsonMarshal(v interface{}) ([]byte, error) {
+
+	var buf bytes.Buffer
+
+	pEnc := json.NewEncoder(&buf)
+	pEnc.SetEscapeHTML(false)
+
+	if E := pEnc.Encode(v); E != nil {
+		return nil, E
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func jsonCell(pCell *GridCell, pal *Palette) JSONCell {
+
+	rChar := pCell.Char
+	if rChar == 0 {
+		rChar = ' '
+	}
+
+	C := JSONCell{
+		Char:      string(rChar),
+		FG:        jsonColor(&pCell.Brush, CIX_FG, pal),
+		BG:        jsonColor(&pCell.Brush, CIX_BG, pal),
+		Unwritten: !pCell.Written,
+	}
+
+	if chr, bOk := map437[rChar]; bOk {
+		n := int(chr)
+		C.Byte = &n
+	}
+
+	for _, A := range sgrNames {
+		if (pCell.Brush.Flags & A.Flag) != 0 {
+			C.Attrs = append(C.Attrs, A.Name)
+		}
+	}
+
+	return C
+}
+
+func jsonColor(pS *SGR, CIX int, pal *Palette) JSONColor {
+
+	sClr := pS.Color[CIX]
+	if IsTermDefault(sClr) {
+		return JSONColor{Type: JSON_CLR_TERMINAL}
+	}
+
+	C := pal.Resolve(pS, CIX)
+	J := JSONColor{RGB: fmt.Sprintf("#%02x%02x%02x", C.R, C.G, C.B)}
+
+	switch len(sClr) {
+
+	case 0:
+		J.Type = JSON_CLR_DEFAULT
+
+	case 1:
+
+		n := sClr[0] % 10
+		if sClr[0] >= 90 {
+			n += 8
+		}
+		J.Type, J.Index = JSON_CLR_CLASSIC, &n
+
+	case 3:
+
+		n := sClr[2]
+		J.Type, J.Index = JSON_CLR_XTERM256, &n
+
+	default:
+		J.Type = JSON_CLR_RGB
+	}
+
+	return J
+}
+
+/*
+	Reads a grid dump (see `Grid.PrintJSON`), either variant.
+	Also returns its palette & SAUCE, nil if missing.
+*/
+func ParseJSONGrid(rdJSON io.Reader) (G Grid, pPal *Palette, pSauce *Sauce, E error) {
+
+	var doc JSONGrid
+	if E = json.NewDecoder(rdJSON).Decode(&doc); E != nil {
+		E = fmt.Errorf("BAD JSON GRID: %s", E.Error())
+		return
+	}
+
+	if doc.Format != JSON_GRID_ID {
+		E = fmt.Errorf("NOT A JSON GRID: %q", doc.Format)
+		return
+	}
+
+	nHeight := doc.Height
+	if len(doc.Rows) > nHeight {
+		nHeight = len(doc.Rows)
+	}
+
+	if !IsBtween(doc.Width, 1, JSON_MAX_WIDTH) || !IsBtween(doc.Height, 1, JSON_MAX_HEIGHT) ||
+		(nHeight > JSON_MAX_HEIGHT) || (doc.Width*nHeight > JSON_MAX_CELLS) {
+
+		E = fmt.Errorf("BAD JSON GRID SIZE %dx%d (MAX %dx%d, %d CELLS)", doc.Width, nHeight, JSON_MAX_WIDTH, JSON_MAX_HEIGHT, JSON_MAX_CELLS)
+		return
+	}
+
+	if len(doc.Palette) > 0 {
+
+		if pPal, E = ParseHexPalette(doc.Palette); E != nil {
+			E = fmt.Errorf("BAD JSON GRID PALETTE: %s", E.Error())
+			return
+		}
+	}
+
+	if G, E = NewGrid(uint(doc.Width)); E != nil {
+		return
+	}
+
+	G.Touch(nHeight)
+
+	for ixRow, sCells := range doc.Rows {
+
+		ixCol := 0
+		for _, J := range sCells {
+
+			var cell GridCell
+			if cell, E = J.gridCell(); E != nil {
+				E = fmt.Errorf("ROW %d: %s", ixRow+1, E.Error())
+				return
+			}
+
+			nRun := J.Run
+			if nRun < 1 {
+				nRun = 1
+			}
+
+			if ixCol+nRun > doc.Width {
+				E = fmt.Errorf("ROW %d EXCEEDS WIDTH %d", ixRow+1, doc.Width)
+				return
+			}
+
+			for ; nRun > 0; nRun-- {
+				G.grid[ixRow][ixCol] = cell
+				ixCol++
+			}
+		}
+	}
+
+	return G, pPal, doc.Sauce, nil
+}
+
+func (J *JSONCell) gridCell() (cell GridCell, E error) {
+
+	switch {
+	case len(J.Char) > 0:
+		cell.Char, _ = utf8.DecodeRuneInString(J.Char)
+	case (J.Byte != nil) && IsBtween(*J.Byte, 0, 255):
+		cell.Char = Array437[*J.Byte]
+	}
+
+	// SPACES AS BLANKS
+	if cell.Char == ' ' {
+		cell.Char = 0
+	}
+
+	cell.Written = !J.Unwritten
+
+	for CIX, J := range []JSONColor{J.FG, J.BG} {
+		if cell.Brush.Color[CIX], E = J.codes(CIX); E != nil {
+			return
+		}
+	}
+
+	for _, szAttr := range J.Attrs {
+
+		bFound := false
+		for _, A := range sgrNames {
+			if A.Name == szAttr {
+				cell.Brush.Flags |= A.Flag
+				bFound = true
+			}
+		}
+
+		if !bFound {
+			E = fmt.Errorf("UNKNOWN ATTRIBUTE: %s", szAttr)
+			return
+		}
+	}
+
+	return
+}
+
+/*
+	SGR color codes for FG or BG (CIX)
+*/
+func (J *JSONColor) codes(CIX int) ([]int, error) {
+
+	nExt, nDark, nBright, nTerm := 38, 30, 90, DEFAULT_FG_TERM
+	if CIX == CIX_BG {
+		nExt, nDark, nBright, nTerm = 48, 40, 100, DEFAULT_BG_TERM
+	}
+
+	fnIndex := func(nMax int) (int, error) {
+
+		if (J.Index == nil) || !IsBtween(*J.Index, 0, nMax) {
+			return 0, fmt.Errorf("BAD %s COLOR INDEX", J.Type)
+		}
+		return *J.Index, nil
+	}
+
+	switch J.Type {
+
+	case JSON_CLR_DEFAULT:
+		return nil, nil
+
+	case JSON_CLR_TERMINAL:
+		return []int{nTerm}, nil
+
+	case JSON_CLR_CLASSIC:
+
+		n, E := fnIndex(15)
+		if E != nil {
+			return nil, E
+		}
+
+		if n < 8 {
+			return []int{nDark + n}, nil
+		}
+		return []int{nBright + n - 8}, nil
+
+	case JSON_CLR_XTERM256:
+
+		n, E := fnIndex(255)
+		if E != nil {
+			return nil, E
+		}
+		return []int{nExt, 5, n}, nil
+
+	case JSON_CLR_RGB:
+
+		v, E := strconv.ParseUint(strings.TrimPrefix(J.RGB, "#"), 16, 32)
+		if (E != nil) || (len(J.RGB) != 7) {
+			return nil, fmt.Errorf("BAD RGB COLOR: %q", J.RGB)
+		}
+		return []int{nExt, 2, int(v >> 16), int((v >> 8) & 0xFF), int(v & 0xFF)}, nil
+	}
+
+	return nil, fmt.Errorf("UNKNOWN COLOR TYPE: %q", J.Type)
+}
@@ -0,0 +1,78 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+
+	pF, E := os.Open(TEST_DIR + "/fruit.ans")
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+	defer pF.Close()
+
+	UM := UTF8Marshaller{Width: 80}
+	G, E := UM.Decode(pF)
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	// ODD CELLS THE ART DOESN'T HAVE
+	G.Put(GridPos{X: 1, Y: 1}, '<', SGR{Flags: SGR_ITALIC | SGR_BLNK_FAST, Color: [CIX_MAX][]int{{38, 5, 208}, {48, 2, 1, 2, 3}}})
+	G.Put(GridPos{X: 2, Y: 1}, '✓', SGR{Color: [CIX_MAX][]int{{39}, {49}}})
+
+	for _, bRLE := range []bool{false, true} {
+
+		var buf bytes.Buffer
+		if E = G.PrintJSON(&buf, &PalC64, nil, bRLE); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		G2, pPal, _, E := ParseJSONGrid(&buf)
+		if E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if (pPal == nil) || (*pPal != PalC64) {
+			t.Errorf("RLE %v: PALETTE %v", bRLE, pPal)
+		}
+
+		if (G2.Width() != G.Width()) || (G2.Height() != G.Height()) {
+			t.Fatalf("RLE %v: %dx%d, EXPECT %dx%d", bRLE, G2.Width(), G2.Height(), G.Width(), G.Height())
+		}
+
+		for ixRow := range G.grid {
+			for ixCol := range G.grid[ixRow] {
+				if !G.grid[ixRow][ixCol].Equal(&G2.grid[ixRow][ixCol]) || (G.grid[ixRow][ixCol].Written != G2.grid[ixRow][ixCol].Written) {
+					t.Errorf("RLE %v: CELL %d,%d: %+v, EXPECT %+v", bRLE, ixCol+1, ixRow+1, G2.grid[ixRow][ixCol], G.grid[ixRow][ixCol])
+				}
+			}
+		}
+	}
+}
+
+func TestJSONBad(t *testing.T) {
+
+	sTests := []string{
+		`{"format":"ansiart2utf8-grid","width":-1,"height":1}`,
+		`{"format":"ansiart2utf8-grid","width":0,"height":1}`,
+		`{"format":"ansiart2utf8-grid","width":80,"height":0}`,
+		`{"format":"ansiart2utf8-grid","width":80,"height":2000000000}`,
+		`{"format":"ansiart2utf8-grid","width":4096,"height":65535}`,
+		`{"format":"ansiart2utf8-grid","width":80,"height":1,"palette":["/etc/passwd"]}`,
+		`{"format":"ansiart2utf8-grid","width":80,"height":1,"palette":["vga"]}`,
+		`{"format":"ansiart2utf8-grid","width":80,"height":1,"palette":["#000000","#ffffff"]}`,
+		`{"format":"ansiart2utf8-grid","width":2,"height":1,"rows":[[{"char":"a","run":3}]]}`,
+		`{"format":"something-else","width":80,"height":1}`,
+	}
+
+	for _, szIn := range sTests {
+		if _, _, _, E := ParseJSONGrid(strings.NewReader(szIn)); E == nil {
+			t.Errorf("%s: EXPECT ERROR", szIn)
+		}
+	}
+}
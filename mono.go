@@ -0,0 +1,110 @@
+package ansiart2utf8
+
+import (
+	"image/color"
+	"math"
+)
+
+/*
+	Maps every color to the gray of its (OKLab) lightness.  Colors become
+	38;2 / 48;2, for `Downsample` to bring back to 256 or 16 colors.
+*/
+func GrayTransform(pal *Palette) *ColorTransform {
+
+	return &ColorTransform{
+		RGB: func(C color.RGBA) color.RGBA {
+			return fromOKLab(okLab{L: toOKLab(C).L})
+		},
+		Pal: pal,
+	}
+}
+
+// SHADES BY COVERAGE: 0, 1/4, 1/2, 3/4, 1
+var sShades = []rune{' ', '░', '▒', '▓', '█'}
+
+/*
+	Renders without color, keeping the shapes color conveyed.
+	Each cell's FG & BG lightness (through `pal`, inverse & conceal
+	resolved) picks:
+		- shade & block chars: the ░▒▓█ shade of their mixed lightness
+		- half blocks: each half on or off
+		- other chars: kept, inverse if darker than their background
+	Only terminal default BG (49) & non-color attributes survive.
+*/
+func (gr *Grid) Monochrome(pal *Palette) {
+
+	const SGR_KEEP = SGR_ITALIC | SGR_UNDERLINE | SGR_BLNK_SLOW | SGR_BLNK_FAST | SGR_STRIKETHROUGH
+
+	fnShade := func(fL float64) rune {
+		return sShades[int(math.Round(math.Max(0, math.Min(1, fL))*float64(len(sShades)-1)))]
+	}
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+
+			pC := &sRow[ix]
+			FG, BG := pal.CellRGB(pC)
+			fFG, fBG := toOKLab(FG).L, toOKLab(BG).L
+			if BG.A == 0 {
+				fBG = 0
+			}
+
+			S := SGR{Flags: pC.Brush.Flags & SGR_KEEP}
+			if IaEqual(pC.Brush.Color[CIX_BG], []int{DEFAULT_BG_TERM}) {
+				S.Color[CIX_BG] = []int{DEFAULT_BG_TERM}
+			}
+
+			fnMix := func(fCover float64) {
+				pC.Char = fnShade((fCover * fFG) + ((1 - fCover) * fBG))
+			}
+
+			fnHalves := func(rFirst, rSecond rune) {
+
+				bFirst, bSecond := fFG >= 0.5, fBG >= 0.5
+				switch {
+				case bFirst && bSecond:
+					pC.Char = '█'
+				case bFirst:
+					pC.Char = rFirst
+				case bSecond:
+					pC.Char = rSecond
+				default:
+					pC.Char = ' '
+				}
+			}
+
+			switch pC.Char {
+
+			case 0, ' ', ' ':
+				fnMix(0)
+			case '░':
+				fnMix(0.25)
+			case '▒':
+				fnMix(0.5)
+			case '▓':
+				fnMix(0.75)
+			case '█':
+				fnMix(1)
+			case '▀':
+				fnHalves('▀', '▄')
+			case '▄':
+				fnHalves('▄', '▀')
+			case '▌':
+				fnHalves('▌', '▐')
+			case '▐':
+				fnHalves('▐', '▌')
+
+			default:
+
+				// TEXT: INVISIBLE IF FG MATCHES BG
+				if math.Abs(fFG-fBG) < 0.05 {
+					fnMix(0)
+				} else if fFG < fBG {
+					S.Flags |= SGR_INVERSE
+				}
+			}
+
+			pC.Brush = S
+		}
+	}
+}
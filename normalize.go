@@ -0,0 +1,216 @@
+package ansiart2utf8
+
+import (
+	"image/color"
+)
+
+/*
+	Bakes attributes into colors:
+		- `bBold`: bold + 30-37 FG becomes 90-97
+		- `bICE`:  blink + 40-47 BG becomes 100-107, without blink (iCE colors)
+*/
+func (pS *SGR) Brighten(bBold, bICE bool) {
+
+	if bBold && ((pS.Flags & SGR_BOLD) != 0) {
+
+		v := DEFAULT_FG
+		if len(pS.Color[CIX_FG]) > 0 {
+			v = pS.Color[CIX_FG][0]
+		}
+
+		if (len(pS.Color[CIX_FG]) < 2) && IsBtween(v, 30, 37) {
+			pS.Color[CIX_FG] = []int{v + 60}
+		}
+	}
+
+	const SGR_BLINK = SGR_BLNK_SLOW | SGR_BLNK_FAST
+	if bICE && ((pS.Flags & SGR_BLINK) != 0) {
+
+		v := DEFAULT_BG
+		if len(pS.Color[CIX_BG]) > 0 {
+			v = pS.Color[CIX_BG][0]
+		}
+
+		if (len(pS.Color[CIX_BG]) < 2) && IsBtween(v, 40, 47) {
+			pS.Color[CIX_BG] = []int{v + 60}
+		}
+
+		pS.Flags &^= SGR_BLINK
+	}
+}
+
+/*
+	Bakes inverse (7) into swapped FG/BG, and conceal (8) into FG = BG.
+	Bold brightening (30-37 FG) is baked in first, as it follows the
+	pre-swap FG on DOS.
+*/
+func (pS *SGR) ResolveAttrs() {
+
+	if (pS.Flags & SGR_INVERSE) != 0 {
+
+		if (pS.Flags & SGR_BOLD) != 0 {
+			pS.Brighten(true, false)
+			pS.Flags &^= SGR_BOLD
+		}
+
+		sFG, sBG := pS.GetColor(CIX_FG, nil), pS.GetColor(CIX_BG, nil)
+		pS.Color[CIX_FG] = moveColor(sBG, CIX_FG)
+		pS.Color[CIX_BG] = moveColor(sFG, CIX_BG)
+		pS.Flags &^= SGR_INVERSE
+	}
+
+	if (pS.Flags & SGR_CONCEAL) != 0 {
+
+		pS.Color[CIX_FG] = moveColor(pS.GetColor(CIX_BG, nil), CIX_FG)
+		pS.Flags &^= SGR_CONCEAL | SGR_BOLD
+	}
+}
+
+/*
+	SGR color codes `sClr` (FG or BG) re-targeted to CIX
+	(i.e. 41 -> 31, 38;5;n -> 48;5;n)
+*/
+func moveColor(sClr []int, CIX int) []int {
+
+	if len(sClr) == 0 {
+		return sClr
+	}
+
+	sRet := append([]int(nil), sClr...)
+	v := sRet[0]
+
+	switch {
+
+	// 38;... / 48;...
+	case len(sRet) > 1:
+		sRet[0] = 38
+		if CIX == CIX_BG {
+			sRet[0] = 48
+		}
+
+	case (CIX == CIX_FG) && (IsBtween(v, 40, 47) || IsBtween(v, 100, 107)):
+		sRet[0] = v - 10
+
+	case (CIX == CIX_BG) && (IsBtween(v, 30, 37) || IsBtween(v, 90, 97)):
+		sRet[0] = v + 10
+	}
+
+	return sRet
+}
+
+/*
+	See `SGR.ResolveAttrs`
+*/
+func (gr *Grid) ResolveAttrs() {
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+			sRow[ix].Brush.ResolveAttrs()
+		}
+	}
+}
+
+// TERMINAL DEFAULT COLORS
+const (
+	DEFAULT_FG_TERM int = 39
+	DEFAULT_BG_TERM int = 49
+)
+
+/*
+	Gives cells without a BG (unwritten, or drawn in the default BG), and
+	cells whose BG resolves (through `pal`) to one of `sTransparent`, the
+	terminal's default background (49).
+	Inverse cells are left alone.
+*/
+func (gr *Grid) ClearBG(sTransparent []color.RGBA, pal *Palette) {
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+
+			pC := &sRow[ix]
+			if (pC.Brush.Flags & SGR_INVERSE) != 0 {
+				continue
+			}
+
+			// SPACES ARE STORED AS 0 TOO: ONLY A MISSING BG MEANS UNWRITTEN
+			bClear := len(pC.Brush.Color[CIX_BG]) == 0
+			if !bClear {
+
+				BG := pal.Resolve(&pC.Brush, CIX_BG)
+				for _, C := range sTransparent {
+					if (C.R == BG.R) && (C.G == BG.G) && (C.B == BG.B) {
+						bClear = true
+						break
+					}
+				}
+			}
+
+			if bClear {
+				pC.Brush.Color[CIX_BG] = []int{DEFAULT_BG_TERM}
+			}
+		}
+	}
+}
+
+/*
+	True if `sClr` is the terminal's default FG or BG (39 / 49)
+*/
+func IsTermDefault(sClr []int) bool {
+	return (len(sClr) == 1) && ((sClr[0] == DEFAULT_FG_TERM) || (sClr[0] == DEFAULT_BG_TERM))
+}
+
+/*
+	See `SGR.Brighten`
+*/
+func (gr *Grid) Brighten(bBold, bICE bool) {
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+			sRow[ix].Brush.Brighten(bBold, bICE)
+		}
+	}
+}
+
+/*
+	`pGrid` with .BoldBright, .ICEColors, .ResolveAttrs, .Transparent,
+	.Grayscale, .Monochrome, .LightBG & .Thumbnail applied, copied if needed
+*/
+func (M UTF8Marshaller) normalize(pGrid *Grid) *Grid {
+
+	if !M.BoldBright && !M.ICEColors && !M.ResolveAttrs && (M.Transparent == nil) &&
+		!M.Grayscale && !M.Monochrome && !M.LightBG && (M.Thumbnail == 0) {
+		return pGrid
+	}
+
+	pal := M.Palette
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	G := pGrid.Clone()
+	G.Brighten(M.BoldBright, M.ICEColors)
+
+	if M.ResolveAttrs {
+		G.ResolveAttrs()
+	}
+
+	if M.Transparent != nil {
+		G.ClearBG(M.Transparent, pal)
+	}
+
+	if M.Monochrome {
+		G.Monochrome(pal)
+	} else if M.Grayscale {
+		G.Transform(GrayTransform(pal))
+	}
+
+	if M.LightBG {
+		G.Transform(LightTransform(pal))
+	}
+
+	if M.Thumbnail > 0 {
+		G = G.HalfBlocks(pal, int(M.Thumbnail))
+	}
+
+	return &G
+}
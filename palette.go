@@ -0,0 +1,331 @@
+package ansiart2utf8
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+	16-color palette: 0..7 dark, 8..15 light (bright)
+*/
+type Palette [16]color.RGBA
+
+/*
+	Builds palette from 16 hex colors (see `ParseHex`), panics on bad input.
+	For built-in tables only.
+*/
+func MustPalette(sHex ...string) (P Palette) {
+
+	if len(sHex) != len(P) {
+		panic(fmt.Sprintf("PALETTE NEEDS %d COLORS, HAS %d", len(P), len(sHex)))
+	}
+
+	for ix := range sHex {
+
+		var E error
+		if P[ix], E = ParseHex(sHex[ix]); E != nil {
+			panic(E.Error())
+		}
+	}
+
+	return
+}
+
+var (
+	// IBM VGA TEXT MODE
+	PalVGA = MustPalette(
+		"#000000", "#AA0000", "#00AA00", "#AA5500", "#0000AA", "#AA00AA", "#00AAAA", "#AAAAAA",
+		"#555555", "#FF5555", "#55FF55", "#FFFF55", "#5555FF", "#FF55FF", "#55FFFF", "#FFFFFF",
+	)
+
+	// `OrigDark` & `OrigLight`
+	PalOrig = func() (P Palette) {
+
+		for ix := range OrigDark {
+			P[ix], _ = ParseHex(OrigDark[ix].Hex)
+			P[ix+8], _ = ParseHex(OrigLight[ix].Hex)
+		}
+
+		return
+	}()
+
+	// WORKBENCH 1.3 DEFAULT PENS 0..7, WHICH THE AMIGA CONSOLE USES FOR 30..37.
+	// NO BRIGHT COLORS: BOLD IS A FONT STYLE THERE.
+	PalAmiga = MustPalette(
+		"#0055AA", "#FFFFFF", "#000022", "#FF8800", "#0000FF", "#FF00FF", "#00FFFF", "#FFFFFF",
+		"#0055AA", "#FFFFFF", "#000022", "#FF8800", "#0000FF", "#FF00FF", "#00FFFF", "#FFFFFF",
+	)
+
+	// C64 (PEPTO), NEAREST VIC-II COLOR FOR EACH ANSI COLOR
+	PalC64 = MustPalette(
+		"#000000", "#68372B", "#588D43", "#6F4F25", "#352879", "#6F3D86", "#70A4B2", "#959595",
+		"#444444", "#9A6759", "#9AD284", "#B8C76F", "#6C5EB5", "#6F3D86", "#70A4B2", "#FFFFFF",
+	)
+
+	// DEFAULT FOR TRUECOLOR & RASTER OUTPUT
+	PalDefault = PalVGA
+)
+
+/*
+	Built-in palettes by name, each distinct
+*/
+var Palettes = map[string]*Palette{
+	"vga":   &PalVGA,
+	"orig":  &PalOrig,
+	"amiga": &PalAmiga,
+	"c64":   &PalC64,
+}
+
+/*
+	Other names for built-in palettes.
+	EGA's default registers, ANSI.SYS & PabloDraw all show the VGA colors.
+*/
+var PaletteAliases = map[string]string{
+	"ega":       "vga",
+	"ansisys":   "vga",
+	"pablodraw": "vga",
+}
+
+/*
+	Names of built-in palettes, sorted, with their aliases:
+	i.e. "vga (ansisys, ega, pablodraw)"
+*/
+func PaletteNames() []string {
+
+	sRet := make([]string, 0, len(Palettes))
+	for k := range Palettes {
+
+		sAlias := []string{}
+		for szAlias, szName := range PaletteAliases {
+			if szName == k {
+				sAlias = append(sAlias, szAlias)
+			}
+		}
+
+		if len(sAlias) > 0 {
+			sort.Strings(sAlias)
+			k += " (" + strings.Join(sAlias, ", ") + ")"
+		}
+
+		sRet = append(sRet, k)
+	}
+
+	sort.Strings(sRet)
+	return sRet
+}
+
+/*
+	Palette by built-in name, palette file (see `LoadPalette`), or custom
+	palette from a comma-separated list of 16 hex colors (0..7 dark, 8..15 bright)
+*/
+func LookupPalette(szSpec string) (*Palette, error) {
+
+	szSpec = strings.TrimSpace(szSpec)
+
+	szName := strings.ToLower(szSpec)
+	if szAlias, bOk := PaletteAliases[szName]; bOk {
+		szName = szAlias
+	}
+
+	if pal, bOk := Palettes[szName]; bOk {
+		return pal, nil
+	}
+
+	if fi, E := os.Stat(szSpec); (E == nil) && fi.Mode().IsRegular() {
+		return LoadPalette(szSpec)
+	}
+
+	sHex := strings.Split(szSpec, ",")
+	if len(sHex) != 16 {
+		return nil, fmt.Errorf("UNKNOWN PALETTE: %s", szSpec)
+	}
+
+	return ParseHexPalette(sHex)
+}
+
+/*
+	Palette from exactly 16 #RRGGBB colors (0-7 dark, 8-15 bright)
+*/
+func ParseHexPalette(sHex []string) (*Palette, error) {
+
+	if len(sHex) != 16 {
+		return nil, fmt.Errorf("PALETTE NEEDS 16 COLORS, NOT %d", len(sHex))
+	}
+
+	pal := new(Palette)
+	for ix := range sHex {
+
+		var E error
+		if pal[ix], E = ParseHex(sHex[ix]); E != nil {
+			return nil, E
+		}
+	}
+
+	return pal, nil
+}
+
+/*
+	Parses comma-separated colors: palette indices (0..15, from `pal`)
+	or hex (see `ParseHex`).  "none" is an empty, non-nil list.
+*/
+func ParseColorList(szList string, pal *Palette) ([]color.RGBA, error) {
+
+	sRet := []color.RGBA{}
+	if strings.EqualFold(strings.TrimSpace(szList), "none") {
+		return sRet, nil
+	}
+
+	for _, szItem := range strings.Split(szList, ",") {
+
+		szItem = strings.TrimSpace(szItem)
+
+		if ix, E := strconv.Atoi(szItem); (E == nil) && (len(szItem) < 3) {
+
+			if (ix < 0) || (ix >= len(pal)) {
+				return nil, fmt.Errorf("PALETTE INDEX OUT OF RANGE: %d", ix)
+			}
+
+			sRet = append(sRet, pal[ix])
+			continue
+		}
+
+		C, E := ParseHex(szItem)
+		if E != nil {
+			return nil, E
+		}
+
+		sRet = append(sRet, C)
+	}
+
+	return sRet, nil
+}
+
+/*
+	Parses `#RRGGBB` or `RRGGBB` into RGBA
+*/
+func ParseHex(szHex string) (C color.RGBA, E error) {
+
+	szHex = strings.TrimPrefix(strings.TrimSpace(szHex), "#")
+	if len(szHex) != 6 {
+		E = fmt.Errorf("BAD HEX COLOR: %s", szHex)
+		return
+	}
+
+	v, E := strconv.ParseUint(szHex, 16, 32)
+	if E != nil {
+		return
+	}
+
+	C = color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}
+	return
+}
+
+// RESETS TERMINAL'S 16-COLOR PALETTE
+const OSC_PALETTE_RESTORE = "\x1b]104\x1b\\"
+
+/*
+	OSC 4 sequence setting the terminal's 16-color palette to `pal`
+	(undo with OSC_PALETTE_RESTORE)
+*/
+func (pal *Palette) OSC4() string {
+
+	var sb strings.Builder
+	sb.WriteString("\x1b]4")
+
+	for ix, C := range pal {
+		fmt.Fprintf(&sb, ";%d;rgb:%02x/%02x/%02x", ix, C.R, C.G, C.B)
+	}
+
+	sb.WriteString("\x1b\\")
+	return sb.String()
+}
+
+/*
+	Converts xterm-256 color index to RGB
+	(0..15 from `pal`, 16..231 from 6x6x6 cube, 232..255 from gray ramp)
+*/
+func Xterm256RGB(n int, pal *Palette) color.RGBA {
+
+	if n < 0 {
+		n = 0
+	}
+
+	if n < 16 {
+		return pal[n]
+	}
+
+	if n < 232 {
+
+		n -= 16
+		fnLevel := func(v int) uint8 {
+			if v == 0 {
+				return 0
+			}
+			return uint8(55 + (v * 40))
+		}
+
+		return color.RGBA{
+			R: fnLevel(n / 36),
+			G: fnLevel((n / 6) % 6),
+			B: fnLevel(n % 6),
+			A: 255,
+		}
+	}
+
+	if n > 255 {
+		n = 255
+	}
+
+	v := uint8(8 + ((n - 232) * 10))
+	return color.RGBA{R: v, G: v, B: v, A: 255}
+}
+
+/*
+	Resolves FG or BG color (CIX) of an SGR to RGB.
+	Bold brightens classic FG colors (30..37), as on DOS.
+*/
+func (pal *Palette) Resolve(pS *SGR, CIX int) color.RGBA {
+
+	sClr := pS.GetColor(CIX, nil)
+	bBold := (CIX == CIX_FG) && ((pS.Flags & SGR_BOLD) != 0)
+
+	switch len(sClr) {
+
+	case 1:
+
+		v := sClr[0]
+		switch {
+		case IsBtween(v, 30, 37):
+			if bBold {
+				return pal[v-30+8]
+			}
+			return pal[v-30]
+		case IsBtween(v, 90, 97):
+			return pal[v-90+8]
+		case IsBtween(v, 40, 47):
+			return pal[v-40]
+		case IsBtween(v, 100, 107):
+			return pal[v-100+8]
+		}
+
+	// 38;5;n / 48;5;n
+	case 3:
+
+		return Xterm256RGB(sClr[2], pal)
+
+	// 38;2;r;g;b / 48;2;r;g;b
+	case 5:
+
+		return color.RGBA{R: uint8(sClr[2]), G: uint8(sClr[3]), B: uint8(sClr[4]), A: 255}
+	}
+
+	if CIX == CIX_FG {
+		return pal[DEFAULT_FG-30]
+	}
+
+	return pal[DEFAULT_BG-40]
+}
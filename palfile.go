@@ -0,0 +1,286 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+/*
+	Loads a 16-color palette file, detected by content:
+		- GIMP palette (.gpl): first 16 colors
+		- Xresources: *colorN: #RRGGBB (also URxvt.colorN, etc.)
+		- iTerm2 (.itermcolors): "Ansi N Color" entries
+		- Alacritty (YAML or TOML): normal/bright black..white
+		- JSON: ["#RRGGBB", ...] or {"colors": ["#RRGGBB", ...]}
+*/
+func LoadPalette(szPath string) (*Palette, error) {
+
+	bsFile, E := ioutil.ReadFile(szPath)
+	if E != nil {
+		return nil, E
+	}
+
+	pal, E := ParsePalette(bsFile)
+	if E != nil {
+		return nil, fmt.Errorf("%s: %s", szPath, E.Error())
+	}
+
+	return pal, nil
+}
+
+/*
+	See `LoadPalette`
+*/
+func ParsePalette(bsData []byte) (*Palette, error) {
+
+	bsTrim := bytes.TrimSpace(bsData)
+
+	switch {
+
+	case bytes.HasPrefix(bsTrim, []byte("GIMP Palette")):
+		return parseGPL(bsTrim)
+
+	case bytes.Contains(bsTrim, []byte("<plist")):
+		return parseITerm(bsTrim)
+
+	case json.Valid(bsTrim):
+		return parseJSONPalette(bsTrim)
+
+	case rxXresColor.Match(bsTrim):
+		return parseXresources(bsTrim)
+
+	case rxTermColor.Match(bsTrim):
+		return parseAlacritty(bsTrim)
+	}
+
+	return nil, fmt.Errorf("UNRECOGNIZED PALETTE FORMAT")
+}
+
+/*
+	Checks that all 16 entries were set
+*/
+func checkPalette(pal *Palette, bsSet []bool) (*Palette, error) {
+
+	nSet := 0
+	for _, b := range bsSet {
+		if b {
+			nSet++
+		}
+	}
+
+	if nSet < len(pal) {
+		return nil, fmt.Errorf("PALETTE DEFINES %d OF %d COLORS", nSet, len(pal))
+	}
+
+	return pal, nil
+}
+
+// GIMP: "R G B<tab>NAME"
+func parseGPL(bsData []byte) (*Palette, error) {
+
+	pal, bsSet := new(Palette), make([]bool, 16)
+	ixColor := 0
+
+	for _, szLine := range strings.Split(string(bsData), "\n") {
+
+		szLine = strings.TrimSpace(szLine)
+		if (len(szLine) == 0) || (szLine[0] < '0') || (szLine[0] > '9') {
+			continue
+		}
+
+		var R, G, B uint8
+		if n, _ := fmt.Sscan(szLine, &R, &G, &B); n != 3 {
+			continue
+		}
+
+		if ixColor < len(pal) {
+			pal[ixColor].R, pal[ixColor].G, pal[ixColor].B, pal[ixColor].A = R, G, B, 255
+			bsSet[ixColor] = true
+		}
+		ixColor++
+	}
+
+	return checkPalette(pal, bsSet)
+}
+
+// `*color0: #000000`, `URxvt.color12: #5555ff`, `#define` LINES IGNORED
+var rxXresColor = regexp.MustCompile(`(?m)^\s*[\w.*-]*color(\d+)\s*:\s*(#[0-9A-Fa-f]{6})`)
+
+func parseXresources(bsData []byte) (*Palette, error) {
+
+	pal, bsSet := new(Palette), make([]bool, 16)
+
+	for _, sMatch := range rxXresColor.FindAllSubmatch(bsData, -1) {
+
+		ix, _ := strconv.Atoi(string(sMatch[1]))
+		if ix >= len(pal) {
+			continue
+		}
+
+		C, E := ParseHex(string(sMatch[2]))
+		if E != nil {
+			return nil, E
+		}
+
+		pal[ix], bsSet[ix] = C, true
+	}
+
+	return checkPalette(pal, bsSet)
+}
+
+var sTermColorNames = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// `black: '#1d1f21'` (YAML) OR `black = "0x1d1f21"` (TOML)
+var rxTermColor = regexp.MustCompile(`(?mi)^\s*(black|red|green|yellow|blue|magenta|cyan|white)\s*[:=]\s*['"]?(?:#|0x)([0-9A-Fa-f]{6})`)
+
+// `normal:` / `bright:` (YAML) OR `[colors.normal]` / `[colors.bright]` (TOML)
+var rxTermSection = regexp.MustCompile(`(?i)^\s*\[?\s*(?:colors\.)?(normal|bright)\s*(?:\]|:)`)
+
+// ANY OTHER YAML SECTION: `dim:`, `cursor:`, ETC
+var rxYAMLSection = regexp.MustCompile(`^\s*[\w-]+\s*:\s*(?:#.*)?$`)
+
+func parseAlacritty(bsData []byte) (*Palette, error) {
+
+	pal, bsSet := new(Palette), make([]bool, 16)
+	nOffset := -1
+
+	for _, szLine := range strings.Split(string(bsData), "\n") {
+
+		if sMatch := rxTermSection.FindStringSubmatch(szLine); sMatch != nil {
+
+			nOffset = 0
+			if strings.EqualFold(sMatch[1], "bright") {
+				nOffset = 8
+			}
+			continue
+		}
+
+		// NEW, UNRELATED SECTION
+		if strings.HasPrefix(strings.TrimSpace(szLine), "[") || rxYAMLSection.MatchString(szLine) {
+			nOffset = -1
+			continue
+		}
+
+		sMatch := rxTermColor.FindStringSubmatch(szLine)
+		if (sMatch == nil) || (nOffset < 0) {
+			continue
+		}
+
+		for ix, szName := range sTermColorNames {
+
+			if strings.EqualFold(sMatch[1], szName) {
+
+				C, E := ParseHex(sMatch[2])
+				if E != nil {
+					return nil, E
+				}
+
+				pal[nOffset+ix], bsSet[nOffset+ix] = C, true
+			}
+		}
+	}
+
+	return checkPalette(pal, bsSet)
+}
+
+/*
+	iTerm2 plist:
+		<key>Ansi 0 Color</key>
+		<dict>
+			<key>Blue Component</key><real>0.0</real>
+			...
+		</dict>
+*/
+func parseITerm(bsData []byte) (*Palette, error) {
+
+	type xNode struct {
+		XMLName xml.Name
+		Value   string  `xml:",chardata"`
+		Items   []xNode `xml:",any"`
+	}
+
+	var plist struct {
+		Dict xNode `xml:"dict"`
+	}
+
+	if E := xml.Unmarshal(bsData, &plist); E != nil {
+		return nil, E
+	}
+
+	pal, bsSet := new(Palette), make([]bool, 16)
+	rxKey := regexp.MustCompile(`^Ansi (\d+) Color$`)
+
+	fnChannel := func(szVal string) uint8 {
+		f, _ := strconv.ParseFloat(strings.TrimSpace(szVal), 64)
+		return uint8(math.Round(math.Max(0, math.Min(1, f)) * 255))
+	}
+
+	szKey := ""
+	for _, item := range plist.Dict.Items {
+
+		if item.XMLName.Local == "key" {
+			szKey = item.Value
+			continue
+		}
+
+		sMatch := rxKey.FindStringSubmatch(szKey)
+		if (sMatch == nil) || (item.XMLName.Local != "dict") {
+			continue
+		}
+
+		ix, _ := strconv.Atoi(sMatch[1])
+		if ix >= len(pal) {
+			continue
+		}
+
+		// COMPONENT KEY/VALUE PAIRS
+		szComp := ""
+		for _, comp := range item.Items {
+
+			if comp.XMLName.Local == "key" {
+				szComp = comp.Value
+				continue
+			}
+
+			switch szComp {
+			case "Red Component":
+				pal[ix].R = fnChannel(comp.Value)
+			case "Green Component":
+				pal[ix].G = fnChannel(comp.Value)
+			case "Blue Component":
+				pal[ix].B = fnChannel(comp.Value)
+			}
+		}
+
+		pal[ix].A, bsSet[ix] = 255, true
+	}
+
+	return checkPalette(pal, bsSet)
+}
+
+func parseJSONPalette(bsData []byte) (*Palette, error) {
+
+	var sHex []string
+
+	if E := json.Unmarshal(bsData, &sHex); E != nil {
+
+		var obj struct {
+			Colors []string `json:"colors"`
+		}
+
+		if E = json.Unmarshal(bsData, &obj); E != nil {
+			return nil, E
+		}
+
+		sHex = obj.Colors
+	}
+
+	return ParseHexPalette(sHex)
+}
@@ -0,0 +1,170 @@
+package ansiart2utf8
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParsePalette(t *testing.T) {
+
+	pal := &PalC64
+	fnHex := func(ix int, szPrefix string) string {
+		C := pal[ix%16]
+		return fmt.Sprintf("%s%02x%02x%02x", szPrefix, C.R, C.G, C.B)
+	}
+
+	// EACH FORMAT, FROM THE FIRST `nColors` OF `pal`
+	fnGPL := func(nColors int) string {
+
+		sz := "GIMP Palette\nName: test\nColumns: 8\n#\n"
+		for ix := 0; ix < nColors; ix++ {
+			C := pal[ix%16]
+			sz += fmt.Sprintf("%3d %3d %3d\tcolor %d\n", C.R, C.G, C.B, ix)
+		}
+		return sz
+	}
+
+	fnXres := func(nColors int) string {
+
+		sz := "#define bg #000000\n*.foreground: #ffffff\n"
+		for ix := 0; ix < nColors; ix++ {
+			sz += fmt.Sprintf("URxvt.color%d: %s\n", ix, fnHex(ix, "#"))
+		}
+		return sz
+	}
+
+	fnAlacritty := func(nColors int, bTOML bool) string {
+
+		sz := "colors:\n  primary:\n    background: '#000000'\n"
+		if bTOML {
+			sz = "[colors.primary]\nbackground = \"0x000000\"\n"
+		}
+
+		for ix := 0; ix < nColors; ix++ {
+
+			if ix%8 == 0 {
+
+				szSect := map[bool]string{true: "normal", false: "bright"}[ix < 8]
+				if bTOML {
+					sz += "[colors." + szSect + "]\n"
+				} else {
+					sz += "  " + szSect + ":\n"
+				}
+			}
+
+			if bTOML {
+				sz += fmt.Sprintf("%s = \"%s\"\n", sTermColorNames[ix%8], fnHex(ix, "0x"))
+			} else {
+				sz += fmt.Sprintf("    %s: '%s'\n", sTermColorNames[ix%8], fnHex(ix, "#"))
+			}
+		}
+
+		// DIM COLORS MUSTN'T LAND ON BRIGHT
+		if bTOML {
+			sz += "[colors.dim]\n"
+		} else {
+			sz += "  dim:\n"
+		}
+
+		for _, szName := range sTermColorNames {
+			if bTOML {
+				sz += fmt.Sprintf("%s = \"0x111111\"\n", szName)
+			} else {
+				sz += fmt.Sprintf("    %s: '#111111'\n", szName)
+			}
+		}
+		return sz
+	}
+
+	fnITerm := func(nColors int) string {
+
+		sz := `<?xml version="1.0" encoding="UTF-8"?>` + "\n<plist version=\"1.0\">\n<dict>\n"
+		for ix := 0; ix < nColors; ix++ {
+			sz += fmt.Sprintf("\t<key>Ansi %d Color</key>\n\t<dict>\n", ix)
+			sz += fmt.Sprintf("\t\t<key>Blue Component</key><real>%f</real>\n", float64(pal[ix].B)/255)
+			sz += "\t\t<key>Color Space</key><string>sRGB</string>\n"
+			sz += fmt.Sprintf("\t\t<key>Green Component</key><real>%f</real>\n", float64(pal[ix].G)/255)
+			sz += fmt.Sprintf("\t\t<key>Red Component</key><real>%f</real>\n", float64(pal[ix].R)/255)
+			sz += "\t</dict>\n"
+		}
+		return sz + "</dict>\n</plist>\n"
+	}
+
+	fnJSON := func(nColors int, bObj bool) string {
+
+		sHex := []string{}
+		for ix := 0; ix < nColors; ix++ {
+			sHex = append(sHex, `"`+fnHex(ix, "#")+`"`)
+		}
+
+		sz := "[" + strings.Join(sHex, ", ") + "]"
+		if bObj {
+			sz = `{"name": "test", "colors": ` + sz + "}"
+		}
+		return sz
+	}
+
+	sTests := []struct {
+		Name string
+		In   string
+		Ok   bool
+	}{
+		{"gpl", fnGPL(16), true},
+		{"gpl, 20 colors", fnGPL(20), true},
+		{"gpl, 15 colors", fnGPL(15), false},
+		{"xresources", fnXres(16), true},
+		{"xresources, 15 colors", fnXres(15), false},
+		{"alacritty yaml", fnAlacritty(16, false), true},
+		{"alacritty toml", fnAlacritty(16, true), true},
+		{"alacritty, no bright", fnAlacritty(8, true), false},
+		{"iterm", fnITerm(16), true},
+		{"iterm, 15 colors", fnITerm(15), false},
+		{"iterm, bad xml", strings.Replace(fnITerm(16), "</dict>\n</plist>", "</plist>", 1), false},
+		{"json", fnJSON(16, false), true},
+		{"json object", fnJSON(16, true), true},
+		{"json, 15 colors", fnJSON(15, false), false},
+		{"json, 17 colors", fnJSON(17, false), false},
+		{"json, bad hex", strings.Replace(fnJSON(16, false), "#", "#zz", 1), false},
+		{"json, named palette", `["vga"]`, false},
+		{"json, path", `["` + strings.Repeat("/etc/passwd,", 15) + `/etc/passwd"]`, false},
+		{"unknown", "16 colors, please", false},
+	}
+
+	for _, T := range sTests {
+
+		pGot, E := ParsePalette([]byte(T.In))
+
+		switch {
+		case T.Ok && (E != nil):
+			t.Errorf("%s: %s", T.Name, E.Error())
+		case T.Ok && (*pGot != *pal):
+			t.Errorf("%s: GOT %v", T.Name, pGot)
+		case !T.Ok && (E == nil):
+			t.Errorf("%s: EXPECT ERROR", T.Name)
+		}
+	}
+}
+
+func TestPaletteNames(t *testing.T) {
+
+	// BUILT-INS DISTINCT, ALIASES OF BUILT-INS
+	for szA, pA := range Palettes {
+		for szB, pB := range Palettes {
+			if (szA != szB) && (*pA == *pB) {
+				t.Errorf("%s & %s ARE THE SAME PALETTE", szA, szB)
+			}
+		}
+	}
+
+	for szAlias, szName := range PaletteAliases {
+
+		if _, bOk := Palettes[szAlias]; bOk {
+			t.Errorf("%s IS BOTH A PALETTE & AN ALIAS", szAlias)
+		}
+
+		if pal, E := LookupPalette(szAlias); (E != nil) || (pal != Palettes[szName]) {
+			t.Errorf("%s: NOT AN ALIAS OF %s", szAlias, szName)
+		}
+	}
+}
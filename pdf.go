@@ -0,0 +1,428 @@
+package ansiart2utf8
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image/color"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PAGE SIZES, IN POINTS (1/72 INCH)
+var PDFPageSizes = map[string][2]float64{
+	"a3":      {841.89, 1190.55},
+	"a4":      {595.28, 841.89},
+	"a5":      {419.53, 595.28},
+	"letter":  {612, 792},
+	"legal":   {612, 1008},
+	"tabloid": {792, 1224},
+}
+
+// HOW ART IS SCALED ONTO THE PAGE
+const (
+	PDF_FIT_NONE  = "none"  // 96 DPI, SHRUNK ONLY IF WIDER THAN THE PAGE
+	PDF_FIT_WIDTH = "width" // FILLS THE PAGE WIDTH, ROWS CONTINUE ON FOLLOWING PAGES
+	PDF_FIT_PAGE  = "page"  // WHOLE ART ON ONE PAGE
+)
+
+var PDFFitModes = []string{PDF_FIT_NONE, PDF_FIT_WIDTH, PDF_FIT_PAGE}
+
+const (
+	PDF_MARGIN  = 36   // POINTS
+	PDF_PX_PT   = 0.75 // ONE PIXEL AT 96 DPI
+	PDF_FONT_ID = "F1"
+)
+
+type PDFOpts struct {
+	PageSize string  // KEY OF PDFPageSizes, "" FOR a4
+	Fit      string  // PDF_FIT_*, "" FOR PDF_FIT_NONE
+	Margin   float64 // POINTS, < 0 FOR NONE, 0 FOR PDF_MARGIN
+}
+
+func PDFPageNames() []string {
+
+	sNames := make([]string, 0, len(PDFPageSizes))
+	for szName := range PDFPageSizes {
+		sNames = append(sNames, szName)
+	}
+
+	sort.Strings(sNames)
+	return sNames
+}
+
+/*
+	PDF objects, numbered from 1, with their file offsets for the xref table
+*/
+type pdfWriter struct {
+	buf      bytes.Buffer
+	sOffsets []int
+}
+
+// RESERVES AN OBJECT NUMBER, FOR REFERENCES BEFORE IT'S WRITTEN
+func (P *pdfWriter) alloc() int {
+
+	P.sOffsets = append(P.sOffsets, 0)
+	return len(P.sOffsets)
+}
+
+func (P *pdfWriter) object(nObj int, szDict string) {
+
+	P.sOffsets[nObj-1] = P.buf.Len()
+	fmt.Fprintf(&P.buf, "%d 0 obj\n%s\nendobj\n", nObj, szDict)
+}
+
+// STREAM OBJECT, FLATE-COMPRESSED WITH bDeflate
+func (P *pdfWriter) stream(nObj int, szDict string, bsData []byte, bDeflate bool) {
+
+	if bDeflate {
+
+		var buf bytes.Buffer
+		pZ := zlib.NewWriter(&buf)
+		pZ.Write(bsData)
+		pZ.Close()
+
+		bsData = buf.Bytes()
+		szDict += " /Filter /FlateDecode"
+	}
+
+	P.sOffsets[nObj-1] = P.buf.Len()
+	fmt.Fprintf(&P.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", nObj, szDict, len(bsData))
+	P.buf.Write(bsData)
+	P.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (P *pdfWriter) finish(nRoot, nInfo int) []byte {
+
+	nXref := P.buf.Len()
+	fmt.Fprintf(&P.buf, "xref\n0 %d\n0000000000 65535 f \n", len(P.sOffsets)+1)
+	for _, nOff := range P.sOffsets {
+		fmt.Fprintf(&P.buf, "%010d 00000 n \n", nOff)
+	}
+
+	fmt.Fprintf(&P.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(P.sOffsets)+1, nRoot, nInfo, nXref)
+	return P.buf.Bytes()
+}
+
+/*
+	Writes the grid as a PDF: background rectangles & text runs, in a Type3
+	font built from the 8x16 glyphs, so blocks & shades print as crisp vector
+	shapes.  Rows are never split between pages.  Title & author come
+	from `pSauce`, if not nil.
+*/
+func (gr *Grid) WritePDF(iWri io.Writer, pal *Palette, opt PDFOpts, pSauce *Sauce) error {
+
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	if opt.PageSize == "" {
+		opt.PageSize = "a4"
+	}
+
+	sizePage, bOk := PDFPageSizes[opt.PageSize]
+	if !bOk {
+		return fmt.Errorf("UNKNOWN PAGE SIZE: %s", opt.PageSize)
+	}
+
+	switch {
+	case opt.Margin < 0:
+		opt.Margin = 0
+	case opt.Margin == 0:
+		opt.Margin = PDF_MARGIN
+	}
+
+	// ART IN PIXELS, AREA IN POINTS
+	nArtW, nArtH := float64(gr.Width()*CELL_W), float64(gr.Height()*CELL_H)
+	fAreaW, fAreaH := sizePage[0]-(2*opt.Margin), sizePage[1]-(2*opt.Margin)
+	if (fAreaW <= 0) || (fAreaH <= 0) {
+		return fmt.Errorf("MARGIN %g TOO WIDE FOR %s", opt.Margin, opt.PageSize)
+	}
+
+	fScale := fAreaW / nArtW
+	switch opt.Fit {
+
+	case "", PDF_FIT_NONE:
+
+		if fScale > PDF_PX_PT {
+			fScale = PDF_PX_PT
+		}
+
+	case PDF_FIT_WIDTH:
+
+	case PDF_FIT_PAGE:
+
+		if (nArtH > 0) && (fAreaH/nArtH < fScale) {
+			fScale = fAreaH / nArtH
+		}
+
+	default:
+		return fmt.Errorf("UNKNOWN FIT MODE: %s", opt.Fit)
+	}
+
+	nPageRows := int((fAreaH + 0.001) / (CELL_H * fScale))
+	if nPageRows < 1 {
+		nPageRows = 1
+	}
+
+	var P pdfWriter
+	P.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	nCatalog, nPages, nFont, nInfo := P.alloc(), P.alloc(), P.alloc(), P.alloc()
+
+	// PAGES
+	bsUsed := [256]bool{}
+	sKids := []string{}
+	for ixRow := 0; (ixRow < gr.Height()) || (ixRow == 0); ixRow += nPageRows {
+
+		ixEnd := ixRow + nPageRows
+		if ixEnd > gr.Height() {
+			ixEnd = gr.Height()
+		}
+
+		fH := float64((ixEnd-ixRow)*CELL_H) * fScale
+		fX := opt.Margin + ((fAreaW - (nArtW * fScale)) / 2)
+		fY := sizePage[1] - opt.Margin - fH
+
+		var bufPage bytes.Buffer
+		fmt.Fprintf(&bufPage, "q %s 0 0 %s %s %s cm\n", pdfNum(fScale), pdfNum(fScale), pdfNum(fX), pdfNum(fY))
+		gr.pdfRows(&bufPage, pal, ixRow, ixEnd, &bsUsed)
+		bufPage.WriteString("Q\n")
+
+		nPage, nContent := P.alloc(), P.alloc()
+		P.object(nPage, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /%s %d 0 R >> >> /Contents %d 0 R >>",
+			nPages, pdfNum(sizePage[0]), pdfNum(sizePage[1]), PDF_FONT_ID, nFont, nContent,
+		))
+		P.stream(nContent, "", bufPage.Bytes(), true)
+
+		sKids = append(sKids, fmt.Sprintf("%d 0 R", nPage))
+	}
+
+	// FONT: USED GLYPHS ONLY
+	var sDiffs, sProcs, sWidths []string
+	for chr, bUsed := range bsUsed {
+
+		sWidths = append(sWidths, strconv.Itoa(CELL_W))
+		if !bUsed {
+			continue
+		}
+
+		szName := fmt.Sprintf("g%02X", chr)
+		nProc := P.alloc()
+		P.stream(nProc, "", pdfGlyph(GetGlyph(Array437[chr])), false)
+
+		sDiffs = append(sDiffs, fmt.Sprintf("%d /%s", chr, szName))
+		sProcs = append(sProcs, fmt.Sprintf("/%s %d 0 R", szName, nProc))
+	}
+
+	// GLYPH SPACE IS PIXELS, 1/16 OF THE FONT SIZE
+	P.object(nFont, fmt.Sprintf(
+		"<< /Type /Font /Subtype /Type3 /FontBBox [0 0 %d %d] /FontMatrix [%s 0 0 %s 0 0] "+
+			"/CharProcs << %s >> /Encoding << /Type /Encoding /Differences [%s] >> "+
+			"/FirstChar 0 /LastChar 255 /Widths [%s] /Resources << >> >>",
+		CELL_W, CELL_H, pdfNum(1.0/CELL_H), pdfNum(1.0/CELL_H),
+		strings.Join(sProcs, " "), strings.Join(sDiffs, " "), strings.Join(sWidths, " "),
+	))
+
+	P.object(nPages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(sKids, " "), len(sKids)))
+	P.object(nCatalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", nPages))
+
+	szInfo := "/Producer (ansiart2utf8)"
+	if pSauce != nil {
+		if len(pSauce.Title) > 0 {
+			szInfo += " /Title " + pdfString(pSauce.Title)
+		}
+		if len(pSauce.Author) > 0 {
+			szInfo += " /Author " + pdfString(pSauce.Author)
+		}
+	}
+	P.object(nInfo, "<< "+szInfo+" >>")
+
+	_, E := iWri.Write(P.finish(nCatalog, nInfo))
+	return E
+}
+
+/*
+	Content for rows [ixRow, ixEnd), in pixels, origin at the bottom left.
+	Marks CP437 codes drawn in `pUsed`.
+*/
+func (gr *Grid) pdfRows(pBuf *bytes.Buffer, pal *Palette, ixRow, ixEnd int, pUsed *[256]bool) {
+
+	pWri := bufio.NewWriter(pBuf)
+	defer pWri.Flush()
+
+	nRows := ixEnd - ixRow
+	nW := gr.Width()
+
+	fnY := func(ix int) int { return (nRows - 1 - (ix - ixRow)) * CELL_H }
+
+	type pdfCell struct {
+		FG, BG color.RGBA
+		Code   byte
+		bInk   bool
+	}
+
+	sCells := make([][]pdfCell, nRows)
+	for ix := ixRow; ix < ixEnd; ix++ {
+
+		sCells[ix-ixRow] = make([]pdfCell, nW)
+		for ixCol := 0; ixCol < nW; ixCol++ {
+
+			cell := gr.Cell(ixCol, ix)
+			FG, BG := pal.CellRGB(&cell)
+
+			chr, bOk := map437[cell.Char]
+			if !bOk {
+				chr = ASCIIFallback(cell.Char)
+			}
+
+			G := GetGlyph(Array437[chr])
+			sCells[ix-ixRow][ixCol] = pdfCell{FG, BG, chr, (G != Glyph{}) && (FG != BG)}
+		}
+	}
+
+	// CLIP, AS BACKGROUNDS OVERLAP RIGHT & DOWN, AGAINST HAIRLINE SEAMS
+	fmt.Fprintf(pWri, "0 0 %d %d re W n\n", nW*CELL_W, nRows*CELL_H)
+
+	// BACKGROUNDS: RUNS OF ONE COLOR, TRANSPARENT LEFT AS PAPER
+	var prevClr color.RGBA
+	fnColor := func(C color.RGBA) {
+
+		if C != prevClr {
+			fmt.Fprintf(pWri, "%s rg\n", pdfRGB(C))
+			prevClr = C
+		}
+	}
+
+	prevClr.A = 1 // MATCHES NO CELL, SO THE FIRST COLOR IS SET
+	for ix := ixRow; ix < ixEnd; ix++ {
+
+		sRow := sCells[ix-ixRow]
+		for ixCol := 0; ixCol < nW; {
+
+			nRun := 1
+			for (ixCol+nRun < nW) && (sRow[ixCol+nRun].BG == sRow[ixCol].BG) {
+				nRun++
+			}
+
+			if BG := sRow[ixCol].BG; BG.A != 0 {
+
+				fnColor(BG)
+				fmt.Fprintf(pWri, "%d %s %s %s re f\n", ixCol*CELL_W, pdfNum(float64(fnY(ix))-0.5), pdfNum(float64(nRun*CELL_W)+0.5), pdfNum(CELL_H+0.5))
+			}
+
+			ixCol += nRun
+		}
+	}
+
+	// TEXT: RUNS OF ONE FG, BLANKS MATCH ANY
+	fmt.Fprintf(pWri, "BT /%s %d Tf\n", PDF_FONT_ID, CELL_H)
+	for ix := ixRow; ix < ixEnd; ix++ {
+
+		sRow := sCells[ix-ixRow]
+		for ixCol := 0; ixCol < nW; ixCol++ {
+
+			if !sRow[ixCol].bInk {
+				continue
+			}
+
+			FG := sRow[ixCol].FG
+			ixLast := ixCol
+			for n := ixCol + 1; (n < nW) && (!sRow[n].bInk || (sRow[n].FG == FG)); n++ {
+				if sRow[n].bInk {
+					ixLast = n
+				}
+			}
+
+			fnColor(FG)
+			fmt.Fprintf(pWri, "1 0 0 1 %d %d Tm <", ixCol*CELL_W, fnY(ix))
+			for n := ixCol; n <= ixLast; n++ {
+
+				chr := sRow[n].Code
+				if !sRow[n].bInk {
+					chr = ' '
+				}
+
+				pUsed[chr] = true
+				fmt.Fprintf(pWri, "%02X", chr)
+			}
+			pWri.WriteString("> Tj\n")
+
+			ixCol = ixLast
+		}
+	}
+	pWri.WriteString("ET\n")
+}
+
+/*
+	Type3 glyph procedure: each run of set pixels as a rectangle,
+	in the fill color of the text
+*/
+func pdfGlyph(G Glyph) []byte {
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d 0 0 0 %d %d d1\n", CELL_W, CELL_W, CELL_H)
+
+	for y, bits := range G {
+
+		for x := 0; x < CELL_W; x++ {
+
+			if (bits & (0x80 >> uint(x))) == 0 {
+				continue
+			}
+
+			x0 := x
+			for (x+1 < CELL_W) && ((bits & (0x80 >> uint(x+1))) != 0) {
+				x++
+			}
+
+			fmt.Fprintf(&buf, "%d %d %d 1 re\n", x0, CELL_H-1-y, x+1-x0)
+		}
+	}
+
+	if G != (Glyph{}) {
+		buf.WriteString("f\n")
+	}
+
+	return buf.Bytes()
+}
+
+func pdfNum(f float64) string {
+
+	sz := strconv.FormatFloat(f, 'f', 4, 64)
+	return strings.TrimSuffix(strings.TrimRight(sz, "0"), ".")
+}
+
+func pdfRGB(C color.RGBA) string {
+
+	fnChan := func(v uint8) string { return strconv.FormatFloat(float64(v)/255, 'f', 3, 64) }
+	return fnChan(C.R) + " " + fnChan(C.G) + " " + fnChan(C.B)
+}
+
+/*
+	Literal string, ( ) \ escaped, non-ASCII as '?'
+*/
+func pdfString(sz string) string {
+
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for _, r := range sz {
+
+		switch {
+		case (r == '(') || (r == ')') || (r == '\\'):
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case (r < 0x20) || (r > 0x7E):
+			sb.WriteByte('?')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte(')')
+
+	return sb.String()
+}
@@ -0,0 +1,63 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+var rxPDFCount = regexp.MustCompile(`/Type /Pages /Kids \[[^\]]*\] /Count (\d+)`)
+
+func TestPDF(t *testing.T) {
+
+	bsIn, E := ioutil.ReadFile(TEST_DIR + "/ti-nex7.ans")
+	if E != nil {
+		t.Fatal(E.Error())
+	}
+
+	sTests := []struct {
+		Fit   string
+		Pages int
+	}{
+		{PDF_FIT_NONE, 6},
+		{PDF_FIT_WIDTH, 7},
+		{PDF_FIT_PAGE, 1},
+	}
+
+	for _, T := range sTests {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 80, Format: FMT_PDF, PageSize: "letter", PageFit: T.Fit, Writer: &buf}
+		if E = UM.Encode(bytes.NewReader(bsIn)); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		bsPDF := buf.Bytes()
+
+		if sMatch := rxPDFCount.FindSubmatch(bsPDF); (sMatch == nil) || (string(sMatch[1]) != strconv.Itoa(T.Pages)) {
+			t.Errorf("%s: EXPECT %d PAGES, GOT %q", T.Fit, T.Pages, sMatch)
+		}
+
+		// EVERY XREF ENTRY AT ITS OBJECT
+		ixXref := bytes.LastIndex(bsPDF, []byte("\nxref\n")) + 1
+		sEntries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(bsPDF[ixXref:], -1)
+		if len(sEntries) == 0 {
+			t.Fatalf("%s: NO XREF", T.Fit)
+		}
+
+		for ix, sEntry := range sEntries {
+
+			nOff, _ := strconv.Atoi(string(sEntry[1]))
+			if !bytes.HasPrefix(bsPDF[nOff:], []byte(fmt.Sprintf("%d 0 obj", ix+1))) {
+				t.Errorf("%s: XREF %d OFF", T.Fit, ix+1)
+			}
+		}
+
+		if !bytes.Contains(bsPDF, []byte(fmt.Sprintf("startxref\n%d\n", ixXref))) {
+			t.Errorf("%s: BAD startxref", T.Fit)
+		}
+	}
+}
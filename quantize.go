@@ -0,0 +1,245 @@
+package ansiart2utf8
+
+import (
+	"image/color"
+	"math"
+	"sync"
+)
+
+// HALF-WEIGHT LIGHTNESS: WITH SMALL PALETTES, KEEP HUE OVER BRIGHTNESS
+// (OTHERWISE #FF0000 MATCHES VGA BROWN BEFORE DARK RED)
+const OKLAB_L_WEIGHT = 0.5
+
+/*
+	Nearest-color matcher for a fixed set of target colors,
+	by distance in OKLab (perceptually uniform) space.
+	Results are cached per input color.
+*/
+type Quantizer struct {
+	ixBase  int
+	sTarget []okLab
+	mtx     sync.Mutex
+	mCache  map[color.RGBA]int
+}
+
+type okLab struct {
+	L, A, B float64
+}
+
+/*
+	Quantizer for `sTarget`, whose first color is color number `ixBase`
+*/
+func NewQuantizer(sTarget []color.RGBA, ixBase int) *Quantizer {
+
+	pQ := &Quantizer{
+		ixBase:  ixBase,
+		sTarget: make([]okLab, len(sTarget)),
+		mCache:  map[color.RGBA]int{},
+	}
+
+	for ix, C := range sTarget {
+		pQ.sTarget[ix] = toOKLab(C)
+	}
+
+	return pQ
+}
+
+/*
+	Color number of the target color nearest to `C`
+*/
+func (pQ *Quantizer) Nearest(C color.RGBA) int {
+
+	C.A = 255
+
+	pQ.mtx.Lock()
+	defer pQ.mtx.Unlock()
+
+	if ix, bOk := pQ.mCache[C]; bOk {
+		return ix
+	}
+
+	lab := toOKLab(C)
+	ixBest, fBest := 0, math.Inf(1)
+
+	for ix, T := range pQ.sTarget {
+
+		dL, dA, dB := OKLAB_L_WEIGHT*(lab.L-T.L), lab.A-T.A, lab.B-T.B
+		if fDist := (dL * dL) + (dA * dA) + (dB * dB); fDist < fBest {
+			ixBest, fBest = ix, fDist
+		}
+	}
+
+	pQ.mCache[C] = pQ.ixBase + ixBest
+	return pQ.ixBase + ixBest
+}
+
+/*
+	https://bottosson.github.io/posts/oklab/
+*/
+func toOKLab(C color.RGBA) okLab {
+
+	fnLinear := func(v uint8) float64 {
+
+		f := float64(v) / 255
+		if f <= 0.04045 {
+			return f / 12.92
+		}
+		return math.Pow((f+0.055)/1.055, 2.4)
+	}
+
+	R, G, B := fnLinear(C.R), fnLinear(C.G), fnLinear(C.B)
+
+	l := math.Cbrt((0.4122214708 * R) + (0.5363325363 * G) + (0.0514459929 * B))
+	m := math.Cbrt((0.2119034982 * R) + (0.6806995451 * G) + (0.1073969566 * B))
+	s := math.Cbrt((0.0883024619 * R) + (0.2817188376 * G) + (0.6299787005 * B))
+
+	return okLab{
+		L: (0.2104542553 * l) + (0.7936177850 * m) - (0.0040720403 * s),
+		A: (1.9779984951 * l) - (2.4285922050 * m) + (0.4505937099 * s),
+		B: (0.0259040371 * l) + (0.7827717662 * m) - (0.8086757660 * s),
+	}
+}
+
+// SUPPORTED COLOR LIMITS
+const (
+	COLORS_8   = 8
+	COLORS_16  = 16
+	COLORS_256 = 256
+)
+
+type quantKey struct {
+	Pal     Palette
+	NColors int
+}
+
+var (
+	mtxQuantizers sync.Mutex
+	mQuantizers   = map[quantKey]*Quantizer{}
+)
+
+/*
+	Shared quantizer for a color limit:
+		- COLORS_256: xterm-256 colors 16..255 (the ones that don't depend on the terminal's theme)
+		- COLORS_16:  `pal` 0..15
+		- COLORS_8:   `pal` 0..7
+*/
+func GetQuantizer(pal *Palette, nColors int) *Quantizer {
+
+	K := quantKey{Pal: *pal, NColors: nColors}
+
+	mtxQuantizers.Lock()
+	defer mtxQuantizers.Unlock()
+
+	if pQ, bOk := mQuantizers[K]; bOk {
+		return pQ
+	}
+
+	var pQ *Quantizer
+
+	switch nColors {
+
+	case COLORS_256:
+
+		sFixed := make([]color.RGBA, 256-16)
+		for ix := range sFixed {
+			sFixed[ix] = Xterm256RGB(ix+16, pal)
+		}
+		pQ = NewQuantizer(sFixed, 16)
+
+	case COLORS_16:
+		pQ = NewQuantizer(pal[:], 0)
+
+	default:
+		pQ = NewQuantizer(pal[:8], 0)
+	}
+
+	mQuantizers[K] = pQ
+	return pQ
+}
+
+/*
+	Rounds a color count to the nearest supported limit at or above it
+	(0 = NO LIMIT)
+*/
+func ColorLimit(nColors int) int {
+
+	switch {
+	case nColors <= 0:
+		return 0
+	case nColors <= COLORS_8:
+		return COLORS_8
+	case nColors <= COLORS_16:
+		return COLORS_16
+	case nColors <= COLORS_256:
+		return COLORS_256
+	}
+
+	return 0
+}
+
+/*
+	Downsamples the SGR color codes `sClr` (FG or BG, per CIX) to `nColors`
+	(see `ColorLimit`), matching against `pal`.
+	Codes already within the limit pass through.
+*/
+func Downsample(sClr []int, CIX int, nColors int, pal *Palette) []int {
+
+	nColors = ColorLimit(nColors)
+	if nColors == 0 {
+		return sClr
+	}
+
+	var C color.RGBA
+
+	switch len(sClr) {
+
+	case 1:
+
+		v := sClr[0]
+		bBright := IsBtween(v, 90, 97) || IsBtween(v, 100, 107)
+		if !bBright || (nColors > COLORS_8) {
+			return sClr
+		}
+
+		// NO BRIGHT COLORS
+		if v >= 100 {
+			C = pal[v-100+8]
+		} else {
+			C = pal[v-90+8]
+		}
+
+	case 3:
+
+		if nColors >= COLORS_256 {
+			return sClr
+		}
+		C = Xterm256RGB(sClr[2], pal)
+
+	case 5:
+
+		C = color.RGBA{R: uint8(sClr[2]), G: uint8(sClr[3]), B: uint8(sClr[4]), A: 255}
+
+	default:
+		return sClr
+	}
+
+	n := GetQuantizer(pal, nColors).Nearest(C)
+
+	if nColors == COLORS_256 {
+		if CIX == CIX_BG {
+			return []int{48, 5, n}
+		}
+		return []int{38, 5, n}
+	}
+
+	nCode := 30 + n
+	if n >= 8 {
+		nCode = 90 + n - 8
+	}
+
+	if CIX == CIX_BG {
+		nCode += 10
+	}
+
+	return []int{nCode}
+}
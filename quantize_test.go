@@ -0,0 +1,61 @@
+package ansiart2utf8
+
+import (
+	"testing"
+)
+
+func TestDownsample(t *testing.T) {
+
+	pal := &PalVGA
+
+	sTests := []struct {
+		In      []int
+		CIX     int
+		NColors int
+		Expect  []int
+	}{
+		{[]int{38, 2, 250, 80, 80}, CIX_FG, 16, []int{91}},
+		{[]int{48, 2, 0, 0, 160}, CIX_BG, 16, []int{44}},
+		{[]int{38, 5, 196}, CIX_FG, 8, []int{31}},
+		{[]int{38, 2, 255, 0, 0}, CIX_FG, 256, []int{38, 5, 196}},
+		{[]int{38, 5, 196}, CIX_FG, 256, []int{38, 5, 196}},
+		{[]int{97}, CIX_FG, 16, []int{97}},
+		{[]int{97}, CIX_FG, 8, []int{37}},
+		{[]int{38, 2, 1, 2, 3}, CIX_FG, 0, []int{38, 2, 1, 2, 3}},
+	}
+
+	for _, T := range sTests {
+
+		if sGot := Downsample(T.In, T.CIX, T.NColors, pal); !IaEqual(sGot, T.Expect) {
+			t.Errorf("%v @ %d COLORS: GOT %v, EXPECT %v", T.In, T.NColors, sGot, T.Expect)
+		}
+	}
+}
+
+func TestTranslateColorsPal(t *testing.T) {
+
+	sXterm := PalVGA.Xterm256Indices()
+
+	sTests := []struct {
+		In      []int
+		Intense bool
+		Expect  []int
+	}{
+		{[]int{31}, false, []int{38, 5, sXterm[1]}},
+		{[]int{31}, true, []int{38, 5, sXterm[9]}},
+		{[]int{94}, false, []int{38, 5, sXterm[12]}},
+		{[]int{44}, true, []int{48, 5, sXterm[4]}},
+		{[]int{107}, false, []int{48, 5, sXterm[15]}},
+		// EXTENDED & OTHER CODES UNTOUCHED
+		{[]int{38, 5, 31}, false, []int{38, 5, 31}},
+		{[]int{48, 2, 100, 40, 45}, false, []int{48, 2, 100, 40, 45}},
+		{[]int{39}, false, []int{39}},
+	}
+
+	for _, T := range sTests {
+
+		if sGot := TranslateColorsPal(T.In, T.Intense, &PalVGA); !IaEqual(sGot, T.Expect) {
+			t.Errorf("%v: GOT %v, EXPECT %v", T.In, sGot, T.Expect)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package ansiart2utf8
+
+import (
+	"image"
+	"image/color"
+)
+
+/*
+	Resolves cell FG/BG to RGB, with inverse & conceal baked in
+	(see `SGR.ResolveAttrs`).  Terminal default BG (49) is transparent.
+*/
+func (pal *Palette) CellRGB(pC *GridCell) (FG, BG color.RGBA) {
+
+	S := pC.Brush
+	S.ResolveAttrs()
+
+	FG = pal.Resolve(&S, CIX_FG)
+	BG = pal.Resolve(&S, CIX_BG)
+
+	if IaEqual(S.Color[CIX_BG], []int{DEFAULT_BG_TERM}) {
+		BG = color.RGBA{}
+	}
+
+	return
+}
+
+/*
+	Renders grid to an image, CELL_W x CELL_H pixels per cell.
+	Uses `PalDefault` when `pal` is nil.
+*/
+func (gr *Grid) Rasterize(pal *Palette) *image.RGBA {
+
+	return gr.RasterizeRect(pal, image.Rect(0, 0, gr.Width(), gr.Height()))
+}
+
+/*
+	Renders cells in `rcCells` (columns/rows, 0-based).
+	Image bounds are in pixels, relative to the whole grid.
+	Cells beyond the grid render blank.
+*/
+func (gr *Grid) RasterizeRect(pal *Palette, rcCells image.Rectangle) *image.RGBA {
+
+	if pal == nil {
+		pal = &PalDefault
+	}
+
+	pImg := image.NewRGBA(image.Rect(
+		rcCells.Min.X*CELL_W, rcCells.Min.Y*CELL_H,
+		rcCells.Max.X*CELL_W, rcCells.Max.Y*CELL_H,
+	))
+
+	for ixRow := rcCells.Min.Y; ixRow < rcCells.Max.Y; ixRow++ {
+
+		for ixCol := rcCells.Min.X; ixCol < rcCells.Max.X; ixCol++ {
+
+			cell := gr.Cell(ixCol, ixRow)
+			FG, BG := pal.CellRGB(&cell)
+			glyph := GetGlyph(cell.Char)
+
+			x0, y0 := ixCol*CELL_W, ixRow*CELL_H
+			for y, bits := range glyph {
+				for x := 0; x < CELL_W; x++ {
+					if (bits & (0x80 >> uint(x))) != 0 {
+						pImg.SetRGBA(x0+x, y0+y, FG)
+					} else {
+						pImg.SetRGBA(x0+x, y0+y, BG)
+					}
+				}
+			}
+		}
+	}
+
+	return pImg
+}
+
+/*
+	Converts `img` to a paletted image with one entry per distinct color.
+	Images with more than 256 colors are mapped to the xterm-256 palette.
+*/
+func IndexImage(img image.Image) *image.Paletted {
+
+	rc := img.Bounds()
+	pRet := image.NewPaletted(rc, nil)
+
+	sIndex := make([]int, rc.Dx()*rc.Dy())
+	sRegs := []color.RGBA{}
+	mRegs := map[color.RGBA]int{}
+
+	ixPix := 0
+	for y := rc.Min.Y; y < rc.Max.Y; y++ {
+		for x := rc.Min.X; x < rc.Max.X; x++ {
+
+			C := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+			ixReg, bOk := mRegs[C]
+			if !bOk {
+				ixReg = len(sRegs)
+				mRegs[C] = ixReg
+				sRegs = append(sRegs, C)
+			}
+
+			sIndex[ixPix] = ixReg
+			ixPix++
+		}
+	}
+
+	// TOO MANY COLORS FOR PALETTE
+	if len(sRegs) > 256 {
+
+		sXterm := make([]color.RGBA, 256)
+		for ix := range sXterm {
+			sXterm[ix] = Xterm256RGB(ix, &PalDefault)
+		}
+
+		sMap := make([]int, len(sRegs))
+		for ix := range sRegs {
+			sMap[ix] = nearestRGB(sRegs[ix], sXterm)
+		}
+
+		for ix := range sIndex {
+			sIndex[ix] = sMap[sIndex[ix]]
+		}
+
+		sRegs = sXterm
+	}
+
+	pRet.Palette = make(color.Palette, len(sRegs))
+	for ix := range sRegs {
+		pRet.Palette[ix] = sRegs[ix]
+	}
+
+	for ix := range sIndex {
+		pRet.Pix[ix] = uint8(sIndex[ix])
+	}
+
+	return pRet
+}
+
+/*
+	Index of closest color in `sPal` (squared RGB distance)
+*/
+func nearestRGB(C color.RGBA, sPal []color.RGBA) int {
+
+	ixBest, nBest := 0, -1
+
+	for ix, P := range sPal {
+
+		dR := int(C.R) - int(P.R)
+		dG := int(C.G) - int(P.G)
+		dB := int(C.B) - int(P.B)
+
+		if nDist := (dR * dR) + (dG * dG) + (dB * dB); (nBest < 0) || (nDist < nBest) {
+			ixBest, nBest = ix, nDist
+		}
+	}
+
+	return ixBest
+}
@@ -0,0 +1,148 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+/*
+	SAUCE metadata record, the last 128 bytes of most scene files
+	(http://www.acid.org/info/sauce/sauce.htm)
+*/
+type Sauce struct {
+	Title    string    `json:"title"`
+	Author   string    `json:"author"`
+	Group    string    `json:"group"`
+	Date     string    `json:"date"` // CCYYMMDD
+	FileSize uint32    `json:"fileSize"`
+	DataType uint8     `json:"dataType"`
+	FileType uint8     `json:"fileType"`
+	TInfo    [4]uint16 `json:"tInfo"` // FOR CHARACTER DATA: WIDTH, HEIGHT
+	Comments []string  `json:"comments,omitempty"`
+	Flags    uint8     `json:"flags"`
+	Font     string    `json:"font"`
+}
+
+const (
+	SAUCE_REC_LEN = 128
+	SAUCE_CMT_LEN = 64
+	SAUCE_ID      = "SAUCE00"
+	SAUCE_CMT_ID  = "COMNT"
+	SAUCE_EOF     = 0x1A
+
+	// DataType & FileType OF .ANS FILES
+	SAUCE_DT_CHARACTER uint8 = 1
+	SAUCE_FT_ANSI      uint8 = 1
+
+	// ANSiFlags: NON-BLINK MODE (iCE COLORS)
+	SAUCE_FLAG_ICE uint8 = 1
+)
+
+/*
+	SAUCE record at the end of `bsFile`, or nil if there isn't one
+*/
+func ParseSauce(bsFile []byte) *Sauce {
+
+	if len(bsFile) < SAUCE_REC_LEN {
+		return nil
+	}
+
+	bsRec := bsFile[len(bsFile)-SAUCE_REC_LEN:]
+	if !bytes.HasPrefix(bsRec, []byte(SAUCE_ID)) {
+		return nil
+	}
+
+	fnStr := func(ixFrom, ixTo int) string {
+		return strings.TrimRight(string(bytes.TrimRight(bsRec[ixFrom:ixTo], "\x00")), " ")
+	}
+
+	S := Sauce{
+		Title:    fnStr(7, 42),
+		Author:   fnStr(42, 62),
+		Group:    fnStr(62, 82),
+		Date:     fnStr(82, 90),
+		FileSize: binary.LittleEndian.Uint32(bsRec[90:]),
+		DataType: bsRec[94],
+		FileType: bsRec[95],
+		Flags:    bsRec[105],
+		Font:     fnStr(106, 128),
+	}
+
+	for ix := range S.TInfo {
+		S.TInfo[ix] = binary.LittleEndian.Uint16(bsRec[96+(ix*2):])
+	}
+
+	// OPTIONAL COMMENT BLOCK BEFORE RECORD
+	nCmt := int(bsRec[104])
+	ixCmt := len(bsFile) - SAUCE_REC_LEN - (nCmt * SAUCE_CMT_LEN) - len(SAUCE_CMT_ID)
+
+	if (nCmt > 0) && (ixCmt >= 0) && bytes.HasPrefix(bsFile[ixCmt:], []byte(SAUCE_CMT_ID)) {
+
+		bsCmt := bsFile[ixCmt+len(SAUCE_CMT_ID):]
+		for ix := 0; ix < nCmt; ix++ {
+			S.Comments = append(S.Comments, strings.TrimRight(string(bsCmt[ix*SAUCE_CMT_LEN:(ix+1)*SAUCE_CMT_LEN]), " \x00"))
+		}
+	}
+
+	return &S
+}
+
+/*
+	True if the art wants blink (SGR 5) as bright background
+*/
+func (pS *Sauce) ICEColors() bool {
+	return (pS.Flags & SAUCE_FLAG_ICE) != 0
+}
+
+/*
+	SAUCE trailer to append to a file: EOF marker, comment block (if any),
+	then the record.  Strings are cut to fit.
+*/
+func (pS *Sauce) Bytes() []byte {
+
+	var buf bytes.Buffer
+	buf.WriteByte(SAUCE_EOF)
+
+	// FIELD PADDED WITH `chrPad`
+	fnPad := func(sz string, nLen int, chrPad byte) []byte {
+
+		bs := bytes.Repeat([]byte{chrPad}, nLen)
+		copy(bs, sz)
+		return bs
+	}
+
+	sCmt := pS.Comments
+	if len(sCmt) > 255 {
+		sCmt = sCmt[:255]
+	}
+
+	if len(sCmt) > 0 {
+
+		buf.WriteString(SAUCE_CMT_ID)
+		for _, szCmt := range sCmt {
+			buf.Write(fnPad(szCmt, SAUCE_CMT_LEN, ' '))
+		}
+	}
+
+	bsRec := make([]byte, SAUCE_REC_LEN)
+	copy(bsRec, SAUCE_ID)
+	copy(bsRec[7:], fnPad(pS.Title, 35, ' '))
+	copy(bsRec[42:], fnPad(pS.Author, 20, ' '))
+	copy(bsRec[62:], fnPad(pS.Group, 20, ' '))
+	copy(bsRec[82:], fnPad(pS.Date, 8, ' '))
+	binary.LittleEndian.PutUint32(bsRec[90:], pS.FileSize)
+	bsRec[94] = pS.DataType
+	bsRec[95] = pS.FileType
+
+	for ix, v := range pS.TInfo {
+		binary.LittleEndian.PutUint16(bsRec[96+(ix*2):], v)
+	}
+
+	bsRec[104] = uint8(len(sCmt))
+	bsRec[105] = pS.Flags
+	copy(bsRec[106:], fnPad(pS.Font, 22, 0))
+
+	buf.Write(bsRec)
+	return buf.Bytes()
+}
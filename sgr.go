@@ -58,10 +58,72 @@ func IaEqual(A, B []int) bool {
 	return true
 }
 
-func (pS *SGR) ToEsc(pPrev *SGR, bAsDiff, bXterm256, bFakeEscape bool) string {
+func (pS *SGR) Equal(pO *SGR) bool {
+
+	return (pS.Flags == pO.Flags) &&
+		IaEqual(pS.Color[CIX_FG], pO.Color[CIX_FG]) &&
+		IaEqual(pS.Color[CIX_BG], pO.Color[CIX_BG])
+}
+
+/*
+	ESCAPE CODE OUTPUT OPTIONS
+*/
+type RenderOpts struct {
+	MaxBytes  int
+	Debug     bool
+	Xterm256  bool
+	TrueColor bool
+	FakeEsc   bool
+	Colors    int
+	Palette   *Palette
+	Caps      *Caps
+	Overflow  string // WHEN A ROW EXCEEDS .MaxBytes: OVERFLOW_*
+	Trim      bool   // DROP TRAILING BLANK CELLS & ROWS, SEE `trimRow`
+	EraseEOL  bool   // WITH .Trim: TRAILING COLORED BLANKS AS ESC[K, NEEDS BCE
+
+	// CHARACTERS PER MESSAGE, SEE `Grid.PrintChat`
+	MaxMessage int
+
+	// SET TERMINAL PALETTE (OSC 4) TO .Palette, OR `PalOrig` IF NIL, THEN
+	// PLAIN 16-COLOR OUTPUT; RESTORE (OSC 104) AFTERWARD UNLESS .KeepPalette
+	SetPalette  bool
+	KeepPalette bool
+}
+
+// ROW BUDGET (.MaxBytes) OVERFLOW MODES
+const (
+	OVERFLOW_TRUNCATE = "truncate" // DROP CELLS THAT DON'T FIT
+	OVERFLOW_WRAP     = "wrap"     // CONTINUE ON FOLLOWING LINES
+	OVERFLOW_FIT      = "fit"      // SHORTER CODES, THEN FEWER COLORS, THEN TRUNCATE
+)
+
+var OverflowModes = []string{OVERFLOW_TRUNCATE, OVERFLOW_WRAP, OVERFLOW_FIT}
+
+func (pOpt *RenderOpts) GetPalette() *Palette {
+
+	if (pOpt == nil) || (pOpt.Palette == nil) {
+		return &PalDefault
+	}
+
+	return pOpt.Palette
+}
+
+func (pS *SGR) ToEsc(pPrev *SGR, bAsDiff bool, pOpt *RenderOpts) string {
 
 	sParts := []int{}
 
+	// TERMINAL CAPABILITIES
+	bColor := true
+	if (pOpt != nil) && (pOpt.Caps != nil) {
+
+		pCaps := pOpt.Caps
+		bColor = !pCaps.Mono
+
+		pOpt = pCaps.Limit(pOpt)
+		S, P := pCaps.Degrade(pS, pOpt), pCaps.Degrade(pPrev, pOpt)
+		pS, pPrev = &S, &P
+	}
+
 	bsIter := []struct {
 		Flag  uint32
 		Set   int
@@ -104,13 +166,17 @@ func (pS *SGR) ToEsc(pPrev *SGR, bAsDiff, bXterm256, bFakeEscape bool) string {
 	// APPEND ANSI CODES FOR FG/BG COLORS
 	for CIX := range []int{CIX_FG, CIX_BG} {
 
+		if !bColor {
+			break
+		}
+
 		// NOTE: NEEDS TO PRE-NORMALIZE CELL COLOR TO CORRECTLY TRACK DIFFERENCES
-		//       (i.e. interplay of bold brightening the color & xterm256 translation)
-		sClr := pS.GetColor(CIX, bXterm256)
+		//       (i.e. interplay of bold brightening the color & xterm256/truecolor translation)
+		sClr := pS.GetColor(CIX, pOpt)
 
 		if bAsDiff {
 
-			sClrPrev := pPrev.GetColor(CIX, bXterm256)
+			sClrPrev := pPrev.GetColor(CIX, pOpt)
 
 			if IaEqual(sClr, sClrPrev) {
 				continue
@@ -127,7 +193,7 @@ func (pS *SGR) ToEsc(pPrev *SGR, bAsDiff, bXterm256, bFakeEscape bool) string {
 
 	// GENERATE ESCAPE CODE
 	pfx := "\x1b["
-	if bFakeEscape {
+	if (pOpt != nil) && pOpt.FakeEsc {
 		pfx = pfx + "96m^[" + pfx + "0m["
 	}
 
@@ -138,7 +204,14 @@ func (pS *SGR) ToEsc(pPrev *SGR, bAsDiff, bXterm256, bFakeEscape bool) string {
 	return pfx + strings.Join(sStr, ";") + "m"
 }
 
-func (pS *SGR) GetColor(CIX int, bXterm256 bool) (RET []int) {
+/*
+	SGR codes for FG or BG color (CIX), translated per `pOpt`:
+		- .TrueColor: 38;2;r;g;b / 48;2;r;g;b from .Palette
+		- .Xterm256:  38;5;n / 48;5;n for classic colors, nearest to .Palette if set
+		- .Colors:    nearest of 8, 16 or 256 colors, for colors beyond it (see `Downsample`)
+		- otherwise, as-is (nil `pOpt` also)
+*/
+func (pS *SGR) GetColor(CIX int, pOpt *RenderOpts) (RET []int) {
 
 	mDefaultClr := map[int]int{
 		CIX_FG: DEFAULT_FG,
@@ -153,9 +226,34 @@ func (pS *SGR) GetColor(CIX int, bXterm256 bool) (RET []int) {
 			RET = pS.Color[CIX]
 		}
 
-		if bXterm256 {
+		if (pOpt == nil) || IsTermDefault(RET) {
+			return
+		}
+
+		if pOpt.TrueColor {
+
+			C := pOpt.GetPalette().Resolve(pS, CIX)
+			RET = []int{38, 2, int(C.R), int(C.G), int(C.B)}
+			if CIX == CIX_BG {
+				RET[0] = 48
+			}
+
+		} else if pOpt.Xterm256 && (len(RET) == 1) && (pOpt.Palette != nil) {
+
+			RET = TranslateColorsPal(RET, (pS.Flags&SGR_BOLD) != 0, pOpt.Palette)
+
+		} else if pOpt.Xterm256 && (len(RET) == 1) {
+
+			// 38;... / 48;... ARE ALREADY EXTENDED, ONLY DOWNSAMPLED
 			RET = TranslateColors(RET, (pS.Flags&SGR_BOLD) != 0)
 		}
+
+		nColors := pOpt.Colors
+		if pOpt.Xterm256 && !pOpt.TrueColor && ((nColors == 0) || (nColors > COLORS_256)) {
+			nColors = COLORS_256
+		}
+
+		RET = Downsample(RET, CIX, nColors, pOpt.GetPalette())
 	}
 
 	return
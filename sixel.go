@@ -0,0 +1,127 @@
+package ansiart2utf8
+
+import (
+	"bufio"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+)
+
+/*
+	Writes `img` as a DCS sixel stream:
+		- one palette register per color of `IndexImage(img)`
+		- one pass per color per 6-pixel band, with run-length encoding
+*/
+func WriteSixel(iWri io.Writer, img image.Image) error {
+
+	rc := img.Bounds()
+	nW, nH := rc.Dx(), rc.Dy()
+
+	pIdx := IndexImage(img)
+	sIndex, sRegs := pIdx.Pix, pIdx.Palette
+
+	pWri := bufio.NewWriter(iWri)
+
+	// DCS: P2=1 (LEAVE UNSET PIXELS ALONE), RASTER ATTRIBUTES 1:1 ASPECT, W x H
+	pWri.WriteString("\x1bP0;1;0q\"1;1;" + strconv.Itoa(nW) + ";" + strconv.Itoa(nH))
+
+	// PALETTE REGISTERS, RGB IN PERCENT
+	fnPct := func(v uint8) string {
+		return strconv.Itoa(((int(v) * 100) + 127) / 255)
+	}
+
+	for ix := range sRegs {
+		C := color.RGBAModel.Convert(sRegs[ix]).(color.RGBA)
+		pWri.WriteString("#" + strconv.Itoa(ix) + ";2;" + fnPct(C.R) + ";" + fnPct(C.G) + ";" + fnPct(C.B))
+	}
+
+	// RUN-LENGTH ENCODE ONE SIXEL ROW
+	fnRLE := func(sRow []byte) {
+
+		// DROP TRAILING BLANKS
+		nEnd := len(sRow)
+		for (nEnd > 0) && (sRow[nEnd-1] == '?') {
+			nEnd--
+		}
+
+		for ix := 0; ix < nEnd; {
+
+			nRun := 1
+			for ((ix + nRun) < nEnd) && (sRow[ix+nRun] == sRow[ix]) {
+				nRun++
+			}
+
+			if nRun > 3 {
+				pWri.WriteString("!" + strconv.Itoa(nRun))
+				pWri.WriteByte(sRow[ix])
+			} else {
+				for i := 0; i < nRun; i++ {
+					pWri.WriteByte(sRow[ix])
+				}
+			}
+
+			ix += nRun
+		}
+	}
+
+	sRow := make([]byte, nW)
+	bsUsed := make([]bool, len(sRegs))
+
+	for yBand := 0; yBand < nH; yBand += 6 {
+
+		nBandH := 6
+		if (yBand + nBandH) > nH {
+			nBandH = nH - yBand
+		}
+
+		// COLORS USED IN THIS BAND
+		for ix := range bsUsed {
+			bsUsed[ix] = false
+		}
+
+		for y := yBand; y < (yBand + nBandH); y++ {
+			for _, ixReg := range sIndex[y*nW : (y+1)*nW] {
+				bsUsed[ixReg] = true
+			}
+		}
+
+		bFirst := true
+		for ixReg, bUsed := range bsUsed {
+
+			// TRANSPARENT: LEAVE UNSET
+			if !bUsed || (color.RGBAModel.Convert(sRegs[ixReg]).(color.RGBA).A == 0) {
+				continue
+			}
+
+			for x := 0; x < nW; x++ {
+
+				var bits byte
+				for y := 0; y < nBandH; y++ {
+					if int(sIndex[((yBand+y)*nW)+x]) == ixReg {
+						bits |= 1 << uint(y)
+					}
+				}
+
+				sRow[x] = '?' + bits
+			}
+
+			// CARRIAGE RETURN TO OVERPRINT BAND WITH NEXT COLOR
+			if !bFirst {
+				pWri.WriteByte('$')
+			}
+			bFirst = false
+
+			pWri.WriteString("#" + strconv.Itoa(ixReg))
+			fnRLE(sRow)
+		}
+
+		// NEXT BAND
+		pWri.WriteByte('-')
+	}
+
+	// ST
+	pWri.WriteString("\x1b\\")
+
+	return pWri.Flush()
+}
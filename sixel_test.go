@@ -0,0 +1,32 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSixelRLE(t *testing.T) {
+
+	pImg := image.NewRGBA(image.Rect(0, 0, 8, 2))
+	for x := 0; x < 8; x++ {
+		pImg.SetRGBA(x, 0, color.RGBA{255, 0, 0, 255})
+		pImg.SetRGBA(x, 1, color.RGBA{0, 0, 255, 255})
+	}
+	pImg.SetRGBA(7, 1, color.RGBA{255, 0, 0, 255})
+
+	var buf bytes.Buffer
+	if oE := WriteSixel(&buf, pImg); oE != nil {
+		t.Fatal(oE.Error())
+	}
+
+	const EXPECT = "\x1bP0;1;0q\"1;1;8;2" +
+		"#0;2;100;0;0#1;2;0;0;100" +
+		"#0!7@B$#1!7A-" +
+		"\x1b\\"
+
+	if buf.String() != EXPECT {
+		t.Fatalf("\nGOT:    %q\nEXPECT: %q", buf.String(), EXPECT)
+	}
+}
@@ -0,0 +1,354 @@
+package ansiart2utf8
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+	TERMINAL CAPTURE MODE
+
+	For replaying output recorded from a modern terminal (asciinema, script):
+		- input is UTF-8, not CP437
+		- ECMA-48 escape parsing (CSI final byte 0x40..0x7E, OSC/DCS strings
+		  skipped up to BEL or ST), unsupported sequences are ignored
+		- optional fixed screen height, with scrolling
+		- deferred auto-wrap at the right margin, as in xterm
+*/
+
+// ESCAPE PARSER STATES
+const (
+	TESC_NONE = iota
+	TESC_START
+	TESC_CSI
+	TESC_INTER
+	TESC_STRING
+	TESC_STRING_ESC
+)
+
+const (
+	CHR_BEL = 0x07
+	CHR_BS  = 0x08
+	CHR_TAB = 0x09
+	CHR_VT  = 0x0B
+	CHR_FF  = 0x0C
+	CHR_DEL = 0x7F
+)
+
+/*
+	Switches interpreter to terminal capture mode.
+	`nRows` > 0 fixes the screen height; output past the bottom scrolls.
+*/
+func (pI *Interpreter) SetTerminal(nRows int) {
+
+	pI.bTerm = true
+	pI.nRows = nRows
+
+	if nRows > 0 {
+		pI.Grid.Touch(nRows)
+	}
+}
+
+func (pI *Interpreter) writeTerm(chr byte) error {
+
+	switch pI.termEsc {
+
+	// AFTER ESC
+	case TESC_START:
+
+		switch {
+
+		case chr == '[':
+			pI.termEsc = TESC_CSI
+			pI.escCur.Reset()
+			pI.escCur.Params = "["
+
+		// OSC, DCS, SOS, PM, APC
+		case strings.IndexByte("]PX^_", chr) != -1:
+			pI.termEsc = TESC_STRING
+
+		// CHARSET DESIGNATION, ETC.
+		case IsBtween(int(chr), 0x20, 0x2F):
+			pI.termEsc = TESC_INTER
+
+		default:
+			pI.termEsc = TESC_NONE
+			pI.execTermEsc(chr)
+		}
+
+		return nil
+
+	case TESC_CSI:
+
+		if IsBtween(int(chr), 0x40, 0x7E) {
+			pI.termEsc = TESC_NONE
+			pI.escCur.Code = rune(chr)
+			pI.execTermCSI()
+		} else {
+			pI.escCur.Params += string(chr)
+		}
+
+		return nil
+
+	case TESC_INTER:
+
+		if IsBtween(int(chr), 0x30, 0x7E) {
+			pI.termEsc = TESC_NONE
+		}
+
+		return nil
+
+	case TESC_STRING:
+
+		if chr == CHR_BEL {
+			pI.termEsc = TESC_NONE
+		} else if chr == CHR_ESCAPE {
+			pI.termEsc = TESC_STRING_ESC
+		}
+
+		return nil
+
+	// ST IS ESC + '\'
+	case TESC_STRING_ESC:
+
+		if chr == '\\' {
+			pI.termEsc = TESC_NONE
+		} else if chr != CHR_ESCAPE {
+			pI.termEsc = TESC_STRING
+		}
+
+		return nil
+	}
+
+	switch chr {
+
+	case CHR_ESCAPE:
+		pI.termEsc = TESC_START
+		pI.bsRune = pI.bsRune[:0]
+		return nil
+
+	case CHR_CR:
+		pI.posCur.X = 1
+		pI.bWrapPending = false
+		return nil
+
+	case CHR_LF, CHR_VT, CHR_FF:
+		pI.lineFeed()
+		return nil
+
+	case CHR_BS:
+		if pI.posCur.X > 1 {
+			pI.posCur.X -= 1
+		}
+		pI.bWrapPending = false
+		return nil
+
+	// NEXT TAB STOP (EVERY 8 COLUMNS)
+	case CHR_TAB:
+		pI.posCur.X = ((((pI.posCur.X - 1) / 8) + 1) * 8) + 1
+		if pI.posCur.X > pI.Grid.Width() {
+			pI.posCur.X = pI.Grid.Width()
+		}
+		return nil
+	}
+
+	// OTHER C0 CONTROLS
+	if (chr < 0x20) || (chr == CHR_DEL) {
+		return nil
+	}
+
+	// COLLECT UTF-8 SEQUENCE
+	pI.bsRune = append(pI.bsRune, chr)
+	if !utf8.FullRune(pI.bsRune) {
+		return nil
+	}
+
+	r, _ := utf8.DecodeRune(pI.bsRune)
+	pI.bsRune = pI.bsRune[:0]
+
+	pI.putTerm(r)
+	return nil
+}
+
+/*
+	Writes `r` at cursor.  At the right margin the wrap is deferred
+	until the next printable character (so CR LF after a full row
+	doesn't leave a blank line).
+*/
+func (pI *Interpreter) putTerm(r rune) {
+
+	if pI.bWrapPending {
+		pI.posCur.X = 1
+		pI.lineFeed()
+	}
+
+	if e2 := pI.Grid.Put(pI.posCur, r, pI.sgrCur); e2 != nil {
+		pI.debug(e2)
+	}
+
+	if pI.posCur.X >= pI.Grid.Width() {
+		pI.bWrapPending = true
+	} else {
+		pI.posCur.X += 1
+	}
+}
+
+/*
+	Cursor down one row, scrolling when at the bottom of a fixed-height screen
+*/
+func (pI *Interpreter) lineFeed() {
+
+	pI.bWrapPending = false
+
+	if (pI.nRows > 0) && (pI.posCur.Y >= pI.nRows) {
+		pI.posCur.Y = pI.nRows
+		pI.Grid.ScrollUp(1)
+		return
+	}
+
+	pI.posCur.Y += 1
+	pI.Grid.Touch(pI.posCur.Y)
+}
+
+/*
+	Single-character escapes (ESC + final byte)
+*/
+func (pI *Interpreter) execTermEsc(chr byte) {
+
+	pI.bWrapPending = false
+
+	switch chr {
+
+	// SAVE CURSOR POS & SGR
+	case '7':
+		pI.posSaved = pI.posCur
+		pI.sgrSaved = pI.sgrCur
+
+	// RESTORE CURSOR POS & SGR
+	case '8':
+		pI.posCur = pI.posSaved
+		pI.sgrCur = pI.sgrSaved
+
+	// INDEX
+	case 'D':
+		pI.lineFeed()
+
+	// NEXT LINE
+	case 'E':
+		pI.posCur.X = 1
+		pI.lineFeed()
+
+	// REVERSE INDEX
+	case 'M':
+		if pI.posCur.Y > 1 {
+			pI.posCur.Y -= 1
+		} else {
+			pI.Grid.ScrollDown(1)
+		}
+
+	// FULL RESET
+	case 'c':
+		pI.Grid.ClearFromPosToEnd(NewPos())
+		pI.posCur, pI.posSaved = NewPos(), NewPos()
+		pI.sgrCur, pI.sgrSaved = SGR{}, SGR{}
+
+	default:
+		pI.debug("IGNORED ESCAPE: ", string(chr))
+	}
+}
+
+/*
+	CSI sequences (ESC [ params final)
+*/
+func (pI *Interpreter) execTermCSI() {
+
+	pC := &pI.escCur
+	pI.bWrapPending = false
+
+	// PRIVATE MODES (ESC[?25l, ETC)
+	if (len(pC.Params) > 1) && (strings.IndexByte("?<=>", pC.Params[1]) != -1) {
+		return
+	}
+
+	fnClampX := func(x int) int {
+		if x > pI.Grid.Width() {
+			return pI.Grid.Width()
+		}
+		return x
+	}
+
+	fnClampY := func(y int) int {
+		if (pI.nRows > 0) && (y > pI.nRows) {
+			return pI.nRows
+		}
+		return y
+	}
+
+	switch pC.Code {
+
+	// TO X,Y
+	case 'H', 'f':
+		pI.posCur.Y = fnClampY(pC.Param(0, 1))
+		pI.posCur.X = fnClampX(pC.Param(1, 1))
+		pI.Grid.Touch(pI.posCur.Y)
+
+	// TO COLUMN
+	case 'G', '`':
+		pI.posCur.X = fnClampX(pC.Param(0, 1))
+
+	// TO ROW
+	case 'd':
+		pI.posCur.Y = fnClampY(pC.Param(0, 1))
+		pI.Grid.Touch(pI.posCur.Y)
+
+	// BEGINNING OF LINE, n LINES DOWN/UP
+	case 'E', 'F':
+		nAmt := pC.Param(0, 1)
+		if pC.Code == 'F' {
+			nAmt = -nAmt
+		}
+		pI.posCur.X = 1
+		pI.Grid.IncClamp(&pI.posCur, 0, nAmt)
+
+	// ERASE n CHARS
+	case 'X':
+		pos := pI.posCur
+		for ix := 0; (ix < pC.Param(0, 1)) && (pos.X <= pI.Grid.Width()); ix++ {
+			pI.Grid.Put(pos, 0, SGR{})
+			pos.X += 1
+		}
+
+	// SGR: UNKNOWN ATTRIBUTES ARE COMMON IN CAPTURES, SKIP INSTEAD OF FAILING
+	case 'm':
+		if pC.Validate() {
+			if e2 := pI.sgrCur.MergeCodes(pC.SubParams); e2 != nil {
+				pI.debug("SGR ERROR ", e2.Error())
+			}
+		} else {
+			pI.debug("INVALID CODE: ", pC.Debug())
+		}
+
+	// ERASE IN DISPLAY: UNLIKE ANSI.SYS, XTERM KEEPS THE CURSOR ON ED 2,
+	// & ED 3 ONLY ERASES SCROLLBACK, WHICH ISN'T KEPT HERE
+	case 'J':
+		if !pC.Validate() {
+			break
+		}
+
+		switch pC.Param(0, 0) {
+		case 2:
+			pI.Grid.ClearFromPosToEnd(NewPos())
+		case 3:
+		default:
+			pI.execEsc()
+		}
+
+	case 'A', 'B', 'C', 'D', 'K', 's', 'u':
+		if pC.Validate() {
+			pI.execEsc()
+		}
+
+	default:
+		pI.debug("IGNORED CODE: ", pC.Debug())
+	}
+}
@@ -0,0 +1,100 @@
+package ansiart2utf8
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+/*
+	7-bit stand-ins for the non-ASCII characters of `Array437`.
+	Box drawing is derived from `mapBoxArms` (see `ASCIIFallback`).
+*/
+var mapASCII = map[rune]byte{
+	'☺': 'o', '☻': 'o', '♥': 'v', '♦': '*', '♣': '%', '♠': '^', '•': '*', '◘': '#',
+	'○': 'o', '◙': '#', '♂': 'o', '♀': 'o', '♪': 'd', '♫': 'd', '☼': '*',
+	'►': '>', '◄': '<', '↕': '|', '‼': '!', '¶': 'P', '§': 'S', '▬': '-', '↨': '|',
+	'↑': '^', '↓': 'v', '→': '>', '←': '<', '∟': 'L', '↔': '-', '▲': '^', '▼': 'v',
+	'⌂': '^',
+
+	'Ç': 'C', 'ü': 'u', 'é': 'e', 'â': 'a', 'ä': 'a', 'à': 'a', 'å': 'a', 'ç': 'c',
+	'ê': 'e', 'ë': 'e', 'è': 'e', 'ï': 'i', 'î': 'i', 'ì': 'i', 'Ä': 'A', 'Å': 'A',
+	'É': 'E', 'æ': 'a', 'Æ': 'A', 'ô': 'o', 'ö': 'o', 'ò': 'o', 'û': 'u', 'ù': 'u',
+	'ÿ': 'y', 'Ö': 'O', 'Ü': 'U', '¢': 'c', '£': 'L', '¥': 'Y', '₧': 'P', 'ƒ': 'f',
+	'á': 'a', 'í': 'i', 'ó': 'o', 'ú': 'u', 'ñ': 'n', 'Ñ': 'N', 'ª': 'a', 'º': 'o',
+	'¿': '?', '⌐': '-', '¬': '-', '½': '/', '¼': '/', '¡': '!', '«': '<', '»': '>',
+
+	'░': '.', '▒': ':', '▓': '#', '█': '#', '▄': '_', '▌': '[', '▐': ']', '▀': '"',
+	'■': '#',
+
+	'α': 'a', 'ß': 'B', 'Γ': 'G', 'π': 'n', 'Σ': 'E', 'σ': 'o', 'µ': 'u', 'τ': 't',
+	'Φ': 'O', 'Θ': 'O', 'Ω': 'O', 'δ': 'd', '∞': '8', 'φ': 'o', 'ε': 'e', '∩': 'n',
+	'≡': '=', '±': '+', '≥': '>', '≤': '<', '⌠': '(', '⌡': ')', '÷': '/', '≈': '~',
+	'°': 'o', '∙': '.', '·': '.', '√': 'v', 'ⁿ': 'n', '²': '2', ' ': ' ',
+}
+
+/*
+	7-bit stand-in for `rChar`: itself if printable ASCII, `+-|` for box
+	drawing, a look-alike for other CP437 characters, '?' otherwise
+*/
+func ASCIIFallback(rChar rune) byte {
+
+	if IsBtween(int(rChar), 0x20, 0x7E) {
+		return byte(rChar)
+	}
+
+	if rChar == 0 {
+		return ' '
+	}
+
+	if chr, bOk := mapASCII[rChar]; bOk {
+		return chr
+	}
+
+	if A, bOk := mapBoxArms[rChar]; bOk {
+
+		bVert := (A.Up != BOX_NONE) || (A.Down != BOX_NONE)
+		bHorz := (A.Left != BOX_NONE) || (A.Right != BOX_NONE)
+
+		switch {
+		case bVert && bHorz:
+			return '+'
+		case bVert:
+			return '|'
+		}
+		return '-'
+	}
+
+	return '?'
+}
+
+/*
+	Writes characters only, no escapes, trailing blanks trimmed from each row.
+	With `bASCII`, non-ASCII characters become `ASCIIFallback`.
+*/
+func (gr *Grid) PrintText(iWri io.Writer, bASCII bool) error {
+
+	pWri := bufio.NewWriter(iWri)
+
+	var sb strings.Builder
+	for _, sRow := range gr.grid {
+
+		sb.Reset()
+		for _, cell := range sRow {
+
+			switch {
+			case bASCII:
+				sb.WriteByte(ASCIIFallback(cell.Char))
+			case cell.Char == 0:
+				sb.WriteByte(' ')
+			default:
+				sb.WriteRune(cell.Char)
+			}
+		}
+
+		pWri.WriteString(strings.TrimRight(sb.String(), "  "))
+		pWri.WriteByte('\n')
+	}
+
+	return pWri.Flush()
+}
@@ -0,0 +1,62 @@
+package ansiart2utf8
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestASCIIFallback(t *testing.T) {
+
+	sTests := []struct {
+		In     rune
+		Expect byte
+	}{
+		{'A', 'A'},
+		{0, ' '},
+		{'é', 'e'},
+		{'¿', '?'},
+		{'▓', '#'},
+		{'─', '-'},
+		{'║', '|'},
+		{'╬', '+'},
+		{'┘', '+'},
+		{'✓', '?'},
+		{'\t', '?'},
+	}
+
+	for _, T := range sTests {
+		if chr := ASCIIFallback(T.In); chr != T.Expect {
+			t.Errorf("%q: GOT %q, EXPECT %q", T.In, chr, T.Expect)
+		}
+	}
+
+	// EVERY CP437 CHARACTER HAS A STAND-IN OF ITS OWN
+	for ix, r := range Array437 {
+		if (ix >= 0x20) && (ix != '?') && (ASCIIFallback(r) == '?') && (r != '¿') {
+			t.Errorf("%02X %q: NO STAND-IN", ix, r)
+		}
+	}
+}
+
+func TestPrintText(t *testing.T) {
+
+	for _, T := range []struct {
+		ASCII  bool
+		Expect string
+	}{
+		{false, "┌─╖ Café\n│░║\n"},
+		{true, "+-+ Cafe\n|.|\n"},
+	} {
+
+		var buf bytes.Buffer
+		UM := UTF8Marshaller{Width: 10, Format: map[bool]string{false: FMT_TEXT, true: FMT_ASCII}[T.ASCII], Writer: &buf}
+		if E := UM.Encode(strings.NewReader("\x1b[31m\xda\xc4\xb7 Caf\x82 \r\n\xb3\xb0\xba")); E != nil {
+			t.Fatal(E.Error())
+		}
+
+		if buf.String() != T.Expect {
+			t.Errorf("ASCII %v: GOT %q, EXPECT %q", T.ASCII, buf.String(), T.Expect)
+		}
+	}
+}
@@ -2,9 +2,13 @@ package ansiart2utf8
 
 import (
 	"bufio"
+	"bytes"
 	"fmt"
+	"image"
+	"image/color"
+	"image/png"
 	"io"
-	"strings"
+	"io/ioutil"
 )
 
 // TRANSLATION ARRAY
@@ -33,244 +37,321 @@ const (
 	CHR_LF     = 0x0A
 )
 
+// OUTPUT FORMATS
+const (
+	FMT_ANSI   = "ansi"
+	FMT_SIXEL  = "sixel"
+	FMT_KITTY  = "kitty"
+	FMT_ITERM2 = "iterm2"
+	FMT_PNG    = "png"
+	FMT_PDF    = "pdf"
+	FMT_GIF    = "gif"
+	FMT_APNG   = "apng"
+	FMT_CAST   = "cast"
+	FMT_TEXT   = "text"
+	FMT_ASCII  = "ascii"
+	FMT_CP437  = "cp437"
+	FMT_BIN    = "bin"
+	FMT_XBIN   = "xbin"
+	FMT_JSON   = "json"
+	FMT_JSONRL = "json-rle"
+)
+
+var OutputFormats = []string{FMT_ANSI, FMT_SIXEL, FMT_KITTY, FMT_ITERM2, FMT_PNG, FMT_PDF, FMT_GIF, FMT_APNG, FMT_CAST, FMT_TEXT, FMT_ASCII, FMT_CP437, FMT_BIN, FMT_XBIN, FMT_MIRC, FMT_DISCORD, FMT_BBCODE, FMT_JSON, FMT_JSONRL}
+
+/*
+	True for formats meant for files rather than terminals
+*/
+func IsBinaryFormat(szFormat string) bool {
+
+	switch szFormat {
+	case FMT_PNG, FMT_PDF, FMT_GIF, FMT_APNG, FMT_CAST, FMT_CP437, FMT_BIN, FMT_XBIN:
+		return true
+	}
+
+	return false
+}
+
 type DebugFunc func(...interface{}) (int, error)
 
 type UTF8Marshaller struct {
 	Width              uint
 	MaxBytes           uint
+	Overflow           string
+	Trim               bool
+	EraseEOL           bool
+	MaxMessage         uint
 	Translate2Xterm256 bool
+	TrueColor          bool
 	FakeEsc            bool
+	Colors             uint
+	Palette            *Palette
+	Caps               *Caps
+	SetPalette         bool
+	KeepPalette        bool
+	BoldBright         bool
+	ICEColors          bool
+	ResolveAttrs       bool
+	Transparent        []color.RGBA
+	LightBG            bool
+	Grayscale          bool
+	Monochrome         bool
+	Thumbnail          uint
+	AppendSauce        bool
+	XBinFont           bool
+	XBinCompress       bool
+	PageSize           string
+	PageFit            string
+	Format             string
+	TileRows           uint
+	Baud               uint
+	FPS                uint
+	Input              string
+	StopTime           float64
+	StopEvent          uint
 	Debug              DebugFunc
+	Warn               DebugFunc
 	Writer             io.Writer
+
+	sauceIn *Sauce
 }
 
 /*
-	ENCODES ANSI ART TO MODERN UTF8 TERMINAL CHARS
+	ENCODES ANSI ART (OR TERMINAL CAPTURE, PER .Input) TO MODERN UTF8 TERMINAL CHARS
 	PRE-RENDERS TO MEMORY (MOTION ESCAPES, COLOR CHANGES, ETC)
-	WRITES OUTPUT, LINE-BY-LINE, TO .Writer
+	WRITES OUTPUT, IN .Format, TO .Writer
 */
-func (M UTF8Marshaller) Encode(rdAnsi io.Reader) (E error) {
-
-	ixByte := -1
-	defer func() {
-
-		if E != nil {
-			E = fmt.Errorf("%s, at index %d", E.Error(), ixByte)
-		}
-	}()
+func (M UTF8Marshaller) Encode(rdAnsi io.Reader) error {
 
 	pRdr := bufio.NewReader(rdAnsi)
-	pGrid, E := NewGrid(M.Width)
-	if E != nil {
-		return
-	}
+	szInput := M.inputFormat(pRdr)
 
-	bEsc := false
-	escCur := EscCode{}
+	var rdIn io.Reader = pRdr
 
-	sgrCur := SGR{}
-	sgrSaved := SGR{}
-	posCur := NewPos()
-	posSaved := NewPos()
+	// SAUCE MAY ASK FOR iCE COLORS
+	if szInput == INPUT_ANSI {
 
-	// NO-OP
-	fnDebug := func(v ...interface{}) (int, error) {
+		bsIn, E := ioutil.ReadAll(pRdr)
+		if E != nil {
+			return E
+		}
 
-		if M.Debug != nil {
-			v = append(v, fmt.Sprintf("at index %d", ixByte))
-			return M.Debug(v...)
+		M.sauceIn = ParseSauce(bsIn)
+		if (M.sauceIn != nil) && M.sauceIn.ICEColors() {
+			M.ICEColors = true
 		}
 
-		return 0, nil
+		rdIn = bytes.NewReader(bsIn)
 	}
 
-CharLoop:
+	// BAUD-RATE PLAYBACK
+	switch M.Format {
+	case FMT_GIF, FMT_APNG:
 
-	for true {
-
-		// TODO: try to extract dims from sauce
-
-		chr, e := pRdr.ReadByte()
-
-		if e == io.EOF {
-			break
-		} else if e != nil {
-			E = e
-			return
+		if szInput != INPUT_ANSI {
+			return fmt.Errorf("%s PLAYBACK NEEDS %s INPUT, NOT %s", M.Format, INPUT_ANSI, szInput)
 		}
-		ixByte += 1
 
-		switch chr {
-
-		// TODO: break at ^ZSAUCE00 (^Z is 26 dec, 0x1A hex)
-		// STOP ON NULL & SAUCE
-		case 0, 26:
-			break CharLoop
+		if M.Format == FMT_GIF {
+			return M.EncodeGIF(rdIn)
 		}
+		return M.EncodeAPNG(rdIn)
+	}
 
-		if chr == CHR_CR {
-
-			posCur.X = 1
-			continue
-
-		} else if chr == CHR_LF {
-
-			// EXTEND ROW
-			posCur.Y += 1
-			pGrid.Touch(posCur.Y)
-			continue
-
-		} else if chr == CHR_ESCAPE {
-
-			// BEGIN ESCAPE CODE
-			bEsc = true
-			escCur.Reset()
-			continue
-
-			// HANDLE ESCAPE CODE SEQUENCE
-		} else if bEsc {
-
-			// ESCAPE CODE TERMINATING CHARS:
-			if strings.IndexByte(SGR_TERMINATORS, chr) == -1 {
-
-				// APPEND COMPONENT OF ESCAPE SEQUENCE
-				escCur.Params += string(chr)
+	var G Grid
+	var E error
+
+	switch szInput {
+	case INPUT_CAST:
+		G, E = M.DecodeCast(pRdr)
+	case INPUT_TYPESCRIPT:
+		G, E = M.DecodeTypescript(pRdr)
+	case INPUT_JSON:
+
+		// DUMP'S PALETTE, UNLESS GIVEN ONE (OR IT'S THE DEFAULT)
+		var pPal *Palette
+		G, pPal, M.sauceIn, E = ParseJSONGrid(pRdr)
+		if (M.Palette == nil) && (pPal != nil) && (*pPal != PalDefault) {
+			M.Palette = pPal
+		}
 
-			} else {
+		if (M.sauceIn != nil) && M.sauceIn.ICEColors() {
+			M.ICEColors = true
+		}
+	case INPUT_ANSI:
+		G, E = M.Decode(rdIn)
+	default:
+		return fmt.Errorf("UNKNOWN INPUT FORMAT: %s", szInput)
+	}
 
-				// EXIT ESCAPE CODE FSM SUCCESSFULLY ON TERMINATING 'm' CHARACTER
+	if E != nil {
+		return E
+	}
 
-				bEsc = false
-				escCur.Code = rune(chr)
+	return M.Render(&G)
+}
 
-				if escCur.Validate() {
+/*
+	.Input, RESOLVING INPUT_AUTO BY PEEKING AT `pRdr`
+*/
+func (M UTF8Marshaller) inputFormat(pRdr *bufio.Reader) string {
 
-					// ONLY RESTORE SGR ESCAPE CODES
-					switch escCur.Code {
+	switch M.Input {
+	case "", INPUT_AUTO:
+		return DetectInput(pRdr)
+	}
 
-					case 'm':
+	return M.Input
+}
 
-						if e2 := sgrCur.MergeCodes(escCur.SubParams); e2 != nil {
-							E = fmt.Errorf("SGR ERROR %s", e2.Error())
-							return
-						}
+/*
+	ESCAPE CODE OPTIONS FOR Grid.Print
+*/
+func (M UTF8Marshaller) RenderOpts() RenderOpts {
+
+	return RenderOpts{
+		MaxBytes:  int(M.MaxBytes),
+		Debug:     M.Debug != nil,
+		Xterm256:  M.Translate2Xterm256,
+		TrueColor: M.TrueColor,
+		FakeEsc:   M.FakeEsc,
+		Colors:    int(M.Colors),
+		Palette:   M.Palette,
+		Caps:      M.Caps,
+		Overflow:  M.Overflow,
+		Trim:      M.Trim,
+		EraseEOL:  M.EraseEOL,
+
+		SetPalette:  M.SetPalette,
+		KeepPalette: M.KeepPalette,
+		MaxMessage:  int(M.MaxMessage),
+	}
+}
 
-					// UP
-					case 'A':
+/*
+	WRITES PRE-RENDERED GRID TO .Writer IN .Format
+*/
+func (M UTF8Marshaller) Render(pGrid *Grid) error {
 
-						pGrid.IncClamp(&posCur, 0, -int(escCur.SubParams[0]))
+	pGrid = M.normalize(pGrid)
 
-					// DOWN
-					case 'B':
+	switch M.Format {
 
-						pGrid.IncClamp(&posCur, 0, int(escCur.SubParams[0]))
+	case "", FMT_ANSI:
+		pGrid.Print(M.Writer, M.RenderOpts())
 
-					// FORWARD
-					case 'C':
+	case FMT_TEXT, FMT_ASCII:
+		return pGrid.PrintText(M.Writer, M.Format == FMT_ASCII)
 
-						pGrid.IncClamp(&posCur, int(escCur.SubParams[0]), 0)
-						// pGrid.Touch(posCur.Y)
+	case FMT_MIRC, FMT_DISCORD, FMT_BBCODE:
+		return pGrid.PrintChat(M.Writer, M.Format, M.RenderOpts())
 
-					// BACK
-					case 'D':
+	case FMT_JSON, FMT_JSONRL:
+		return pGrid.PrintJSON(M.Writer, M.Palette, M.sauceIn, M.Format == FMT_JSONRL)
 
-						pGrid.IncClamp(&posCur, -int(escCur.SubParams[0]), 0)
+	case FMT_CP437, FMT_BIN, FMT_XBIN:
 
-					// NOTE: NOT ANSI.SYS
-					case 'E', 'F', 'G':
-						// E: beginning on line, n lines down
-						// F: beginning on line, n lines up
-						// G: cursor to column n
+		var R BinReport
+		var E error
 
-					// TO X,Y
-					case 'H', 'f':
+		switch M.Format {
+		case FMT_CP437:
+			R, E = pGrid.PrintCP437(M.Writer, M.Palette, M.outputSauce())
+		case FMT_BIN:
+			R, E = pGrid.WriteBin(M.Writer, M.Palette, M.newSauce())
+		default:
+			R, E = pGrid.WriteXBin(M.Writer, M.Palette, M.XBinFont, M.XBinCompress, M.newSauce())
+		}
 
-						posCur.Y = int(escCur.SubParams[0])
-						posCur.X = int(escCur.SubParams[1])
-						pGrid.Touch(posCur.Y)
+		if M.Warn != nil {
+			for _, szLine := range R.Lines() {
+				M.Warn(M.Format, szLine)
+			}
+		}
 
-					case 'J':
+		return E
 
-						switch escCur.SubParams[0] {
+	case FMT_PNG:
+		return png.Encode(M.Writer, pGrid.Rasterize(M.Palette))
 
-						// clear from cursor to end of screen
-						case 0:
-							pGrid.ClearFromPosToEnd(posCur)
+	case FMT_PDF:
+		return pGrid.WritePDF(M.Writer, M.Palette, PDFOpts{PageSize: M.PageSize, Fit: M.PageFit}, M.sauceIn)
 
-						// clear from cursor to beginning of screen
-						case 1:
-							pGrid.ClearFromPosToBegin(posCur)
+	case FMT_CAST:
 
-						// clear entire screen, move cursor to upper-left
-						case 2:
-							posCur.X, posCur.Y = 1, 1
-							pGrid.ClearFromPosToEnd(posCur)
+		// ANSI OUTPUT, PACED AT .Baud
+		var buf bytes.Buffer
+		pGrid.Print(&buf, M.RenderOpts())
 
-						// clear entire screen, reset scrollback buffer
-						case 3:
-							pGrid.ClearFromPosToEnd(GridPos{1, 1})
-						}
+		hdr := CastHeader{
+			Width:  pGrid.Width(),
+			Height: pGrid.Height(),
+			Env:    map[string]string{"TERM": "xterm-256color"},
+		}
 
-					case 'K':
+		// FINAL LF WOULD SCROLL THE FIRST ROW OFF A .Height TALL SCREEN
+		bsStream := bytes.TrimSuffix(buf.Bytes(), []byte("\n"))
 
-						switch escCur.SubParams[0] {
+		return WriteCast(M.Writer, hdr, bsStream, M.BytesPerFrame(), 1/float64(M.fps()))
 
-						// clear from cursor to end of line
-						case 0:
-							pGrid.ClearLine(posCur, false)
+	case FMT_SIXEL, FMT_KITTY, FMT_ITERM2:
 
-						// clear from cursor to beginning of line
-						case 1:
-							pGrid.ClearLine(posCur, true)
+		fnWrite := map[string]func(io.Writer, image.Image) error{
+			FMT_SIXEL:  WriteSixel,
+			FMT_KITTY:  WriteKitty,
+			FMT_ITERM2: WriteITerm2,
+		}[M.Format]
 
-						// clear entire line
-						case 2:
-							pGrid.ClearLine(GridPos{X: 1, Y: posCur.Y}, false)
-						}
+		// ONE IMAGE PER .TileRows CHARACTER ROWS
+		sTiles := TileImage(pGrid.Rasterize(M.Palette), int(M.TileRows)*CELL_H)
+		for ix, img := range sTiles {
 
-					// NO-OP: NOT ANSI.SYS
-					case 'S', 'T':
-						// S: scroll page up by n lines
-						// T: scroll page down by n lines
+			if ix > 0 {
+				if _, E := io.WriteString(M.Writer, "\n"); E != nil {
+					return E
+				}
+			}
 
-					// SAVE CURSOR POS & SGR
-					case 's':
+			if E := fnWrite(M.Writer, img); E != nil {
+				return E
+			}
+		}
 
-						posSaved = posCur
-						sgrSaved = sgrCur
+	default:
+		return fmt.Errorf("UNKNOWN OUTPUT FORMAT: %s", M.Format)
+	}
 
-					// RESTORE CURSOR POS & SGR
-					case 'u':
+	return nil
+}
 
-						posCur = posSaved
-						sgrCur = sgrSaved
+/*
+	PRE-RENDERS ANSI ART TO GRID (MOTION ESCAPES, COLOR CHANGES, ETC)
+*/
+func (M UTF8Marshaller) Decode(rdAnsi io.Reader) (G Grid, E error) {
 
-					default:
+	pI, E := M.NewInterpreter()
+	if E != nil {
+		return
+	}
 
-						E = fmt.Errorf("UNHANDLED CODE %s", escCur.Debug())
-						return
-					}
+	pRdr := bufio.NewReader(rdAnsi)
 
-				} else {
+	for !pI.Done() {
 
-					fnDebug("INVALID CODE: ", escCur.Debug())
-				}
+		chr, e := pRdr.ReadByte()
 
-				continue
-			}
+		if e == io.EOF {
+			break
+		} else if e != nil {
+			E = fmt.Errorf("%s, at index %d", e.Error(), pI.Count())
+			return
 		}
 
-		// HANDLE WRITABLE CHARACTERS OUTSIDE OF ESCAPE MODE
-		if !bEsc {
-
-			if e2 := pGrid.Put(posCur, Array437[chr], sgrCur); e2 != nil {
-				fnDebug(e2)
-			}
-
-			pGrid.Inc(&posCur, 1)
+		if E = pI.WriteByte(chr); E != nil {
+			return
 		}
 	}
 
-	pGrid.Print(M.Writer, int(M.MaxBytes), M.Debug != nil, M.Translate2Xterm256, M.FakeEsc)
+	G = pI.Grid
 	return
 }
@@ -0,0 +1,204 @@
+package ansiart2utf8
+
+import (
+	"image/color"
+	"math"
+	"sort"
+)
+
+/*
+	Per-color transform over SGR colors, resolved through .Pal.
+	With .Keep16, classic & xterm-256 colors stay classic & xterm-256
+	(per .Index if set, else nearest match), otherwise every color
+	becomes 38;2 / 48;2.
+*/
+type ColorTransform struct {
+	RGB    func(color.RGBA) color.RGBA
+	Keep16 bool
+	Index  []int // PALETTE INDEX MAP FOR CLASSIC COLORS (OPTIONAL)
+	Pal    *Palette
+}
+
+/*
+	Applies `pT` to FG & BG of `pS`.  Bold brightening of classic FG colors
+	is baked in (and bold dropped), so the result doesn't depend on whether
+	the terminal brightens bold.
+*/
+func (pT *ColorTransform) Apply(pS *SGR) {
+
+	S := *pS
+
+	for CIX := range S.Color {
+
+		sClr := pS.Color[CIX]
+		if IsTermDefault(sClr) {
+			continue
+		}
+
+		C := pT.RGB(pT.Pal.Resolve(pS, CIX))
+
+		nExt, nDark, nBright := 38, 30, 90
+		if CIX == CIX_BG {
+			nExt, nDark, nBright = 48, 40, 100
+		}
+
+		switch {
+
+		case pT.Keep16 && (len(sClr) < 2):
+
+			n := paletteIndex(pS, CIX)
+			if (n < 0) || (pT.Index == nil) {
+				n = GetQuantizer(pT.Pal, COLORS_16).Nearest(C)
+			} else {
+				n = pT.Index[n]
+			}
+
+			if n < 8 {
+				S.Color[CIX] = []int{nDark + n}
+			} else {
+				S.Color[CIX] = []int{nBright + n - 8}
+			}
+
+		case pT.Keep16 && (len(sClr) == 3):
+
+			S.Color[CIX] = []int{nExt, 5, GetQuantizer(pT.Pal, COLORS_256).Nearest(C)}
+
+		default:
+
+			S.Color[CIX] = []int{nExt, 2, int(C.R), int(C.G), int(C.B)}
+		}
+	}
+
+	// BOLD WAS PART OF THE COLOR
+	if len(pS.Color[CIX_FG]) < 2 {
+		S.Flags &^= SGR_BOLD
+	}
+
+	*pS = S
+}
+
+/*
+	Palette index (0..15) of classic FG or BG color, with bold brightening;
+	-1 if not classic
+*/
+func paletteIndex(pS *SGR, CIX int) int {
+
+	v := pS.GetColor(CIX, nil)[0]
+	bBold := (pS.Flags & SGR_BOLD) != 0
+
+	switch {
+	case (CIX == CIX_FG) && IsBtween(v, 30, 37) && bBold:
+		return v - 30 + 8
+	case (CIX == CIX_FG) && IsBtween(v, 30, 37):
+		return v - 30
+	case (CIX == CIX_FG) && IsBtween(v, 90, 97):
+		return v - 90 + 8
+	case (CIX == CIX_BG) && IsBtween(v, 40, 47):
+		return v - 40
+	case (CIX == CIX_BG) && IsBtween(v, 100, 107):
+		return v - 100 + 8
+	}
+
+	return -1
+}
+
+/*
+	See `ColorTransform.Apply`
+*/
+func (gr *Grid) Transform(pT *ColorTransform) {
+
+	for _, sRow := range gr.grid {
+		for ix := range sRow {
+			pT.Apply(&sRow[ix].Brush)
+		}
+	}
+}
+
+/*
+	Remaps colors for light backgrounds.  Grays have their lightness inverted,
+	so black & white (and dark & light gray) trade places.  Colors keep hue
+	& chroma, their lightness pulled toward the middle by how saturated
+	they are, so a bright yellow doesn't turn black.  The same map applies
+	to FG & BG, so ░▒▓ shading between them stays coherent.
+*/
+func LightTransform(pal *Palette) *ColorTransform {
+
+	// CHROMA AT WHICH A COLOR IS FULLY "COLORFUL"
+	const FULL_CHROMA = 0.1
+
+	fnRGB := func(C color.RGBA) color.RGBA {
+
+		lab := toOKLab(C)
+		fChroma := math.Min(1, math.Hypot(lab.A, lab.B)/FULL_CHROMA)
+		fKeep := 0.5 * fChroma
+
+		lab.L = ((1 - lab.L) * (1 - fKeep)) + (lab.L * fKeep)
+		return fromOKLab(lab)
+	}
+
+	// CLASSIC COLORS KEEP THEIR HUE SLOT, PICKING DARK OR BRIGHT BY LIGHTNESS;
+	// THE FOUR GRAYS (0, 7, 8, 15) TRADE PLACES BY LIGHTNESS RANK
+	sIndex := make([]int, len(pal))
+	sGrays := []int{0, 8, 7, 15}
+	sort.SliceStable(sGrays, func(i, j int) bool {
+		return toOKLab(pal[sGrays[i]]).L < toOKLab(pal[sGrays[j]]).L
+	})
+
+	for ix, n := range sGrays {
+		sIndex[n] = sGrays[len(sGrays)-1-ix]
+	}
+
+	for ix := range pal {
+
+		nSlot := ix % 8
+		if (nSlot == 0) || (nSlot == 7) {
+			continue
+		}
+
+		fL := toOKLab(fnRGB(pal[ix])).L
+		fDark := math.Abs(toOKLab(pal[nSlot]).L - fL)
+		fBright := math.Abs(toOKLab(pal[nSlot+8]).L - fL)
+
+		sIndex[ix] = nSlot
+		if fBright < fDark {
+			sIndex[ix] = nSlot + 8
+		}
+	}
+
+	return &ColorTransform{
+		RGB:    fnRGB,
+		Keep16: true,
+		Index:  sIndex,
+		Pal:    pal,
+	}
+}
+
+/*
+	Inverse of `toOKLab`, clamped to sRGB
+*/
+func fromOKLab(lab okLab) color.RGBA {
+
+	l := lab.L + (0.3963377774 * lab.A) + (0.2158037573 * lab.B)
+	m := lab.L - (0.1055613458 * lab.A) - (0.0638541728 * lab.B)
+	s := lab.L - (0.0894841775 * lab.A) - (1.2914855480 * lab.B)
+
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	R := (4.0767416621 * l) - (3.3077115913 * m) + (0.2309699292 * s)
+	G := (-1.2684380046 * l) + (2.6097574011 * m) - (0.3413193965 * s)
+	B := (-0.0041960863 * l) - (0.7034186147 * m) + (1.7076147010 * s)
+
+	fnGamma := func(f float64) uint8 {
+
+		f = math.Max(0, math.Min(1, f))
+		if f <= 0.0031308 {
+			f *= 12.92
+		} else {
+			f = (1.055 * math.Pow(f, 1/2.4)) - 0.055
+		}
+
+		return uint8(math.Round(f * 255))
+	}
+
+	return color.RGBA{R: fnGamma(R), G: fnGamma(G), B: fnGamma(B), A: 255}
+}
@@ -65,3 +65,46 @@ func TranslateColors(sSGR []int, bIntense bool) []int {
 
 	return sRet
 }
+
+/*
+	Like `TranslateColors`, but picks the nearest xterm-256 color (16..255,
+	which don't depend on the terminal's theme) to each color of `pal`.
+	Only a single classic code (30-37, 90-97, 40-47, 100-107) is mapped;
+	anything else (38;..., 48;...) is returned unchanged.
+*/
+func TranslateColorsPal(sSGR []int, bIntense bool, pal *Palette) []int {
+
+	if len(sSGR) != 1 {
+		return sSGR
+	}
+
+	sXterm := pal.Xterm256Indices()
+
+	switch v := sSGR[0]; {
+	case IsBtween(v, 30, 37) && bIntense:
+		return []int{38, 5, sXterm[v-30+8]}
+	case IsBtween(v, 30, 37):
+		return []int{38, 5, sXterm[v-30]}
+	case IsBtween(v, 90, 97):
+		return []int{38, 5, sXterm[v-90+8]}
+	case IsBtween(v, 40, 47):
+		return []int{48, 5, sXterm[v-40]}
+	case IsBtween(v, 100, 107):
+		return []int{48, 5, sXterm[v-100+8]}
+	}
+
+	return sSGR
+}
+
+/*
+	Nearest xterm-256 color (16..255) to each palette entry
+*/
+func (pal *Palette) Xterm256Indices() (RET [16]int) {
+
+	pQ := GetQuantizer(pal, COLORS_256)
+	for ix, C := range pal {
+		RET[ix] = pQ.Nearest(C)
+	}
+
+	return
+}